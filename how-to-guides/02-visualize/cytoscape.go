@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/cayleygraph/cayley/quad"
+)
+
+type cyElement struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+type cyStyle struct {
+	Selector string            `json:"selector"`
+	Style    map[string]string `json:"style"`
+}
+
+type cyGraph struct {
+	Elements struct {
+		Nodes []cyElement `json:"nodes"`
+		Edges []cyElement `json:"edges"`
+	} `json:"elements"`
+	Style []cyStyle `json:"style"`
+}
+
+// WriteCytoscape writes the graph as Cytoscape.js JSON. The output can be
+// passed as is to cytoscape({...}): it has the elements and a style that
+// colors nodes by their rdf:type like in WriteDOT.
+func WriteCytoscape(w io.Writer, g *Graph, opts Options) error {
+	var cy cyGraph
+
+	ids := g.IDs()
+	types := make(map[quad.IRI]bool)
+
+	for _, n := range g.Nodes {
+		data := map[string]interface{}{
+			"id":    ids[n.ID],
+			"iri":   valueLabel(n.ID, opts),
+			"label": Title(n, opts),
+		}
+		if n.Type != "" {
+			data["type"] = string(n.Type)
+			types[n.Type] = true
+		}
+		for _, p := range n.Props {
+			// never let a property hide the id or the label
+			if name := opts.Shorten(p.Name); data[name] == nil {
+				data[name] = valueLabel(p.Value, opts)
+			}
+		}
+		cy.Elements.Nodes = append(cy.Elements.Nodes, cyElement{Data: data})
+	}
+
+	for i, e := range g.Edges {
+		cy.Elements.Edges = append(cy.Elements.Edges, cyElement{Data: map[string]interface{}{
+			"id":     fmt.Sprintf("e%d", i),
+			"source": ids[e.From],
+			"target": ids[e.To],
+			"label":  opts.Shorten(e.Label),
+		}})
+	}
+
+	cy.Style = []cyStyle{
+		{Selector: "node", Style: map[string]string{
+			"label":            "data(label)",
+			"background-color": hex(colorOf(untypedStyle.Color)),
+		}},
+		{Selector: "edge", Style: map[string]string{
+			"label":              "data(label)",
+			"curve-style":        "bezier",
+			"target-arrow-shape": "triangle",
+		}},
+	}
+
+	var sorted []quad.IRI
+	for t := range types {
+		sorted = append(sorted, t)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	for _, t := range sorted {
+		cy.Style = append(cy.Style, cyStyle{
+			Selector: fmt.Sprintf("node[type = %q]", string(t)),
+			Style: map[string]string{
+				"background-color": hex(colorOf(TypeStyle(t).Color)),
+			},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cy)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cayleygraph/cayley/quad"
+)
+
+// Style is how nodes of one rdf:type are drawn.
+type Style struct {
+	Color string
+}
+
+// DefaultStyles are the colors we used to add by hand to graph.dot.
+var DefaultStyles = map[quad.IRI]Style{
+	"Clinic":                           {Color: "green"},
+	"Admin":                            {Color: "lightblue"},
+	"schema:OpeningHoursSpecification": {Color: "khaki"},
+}
+
+// untypedStyle is used for nodes without a type, ex: schema:Monday.
+var untypedStyle = Style{Color: "lightgrey"}
+
+// StyleOf returns the style of a node.
+func StyleOf(n *Node) Style {
+	return TypeStyle(n.Type)
+}
+
+// TypeStyle returns the style of the nodes of a type.
+func TypeStyle(t quad.IRI) Style {
+	if t == "" {
+		return untypedStyle
+	}
+	if s, ok := DefaultStyles[t]; ok {
+		return s
+	}
+	return Style{Color: "white"}
+}
+
+// Title is the first line of a node label: the short id of a node and its
+// type, if any.
+func Title(n *Node, opts Options) string {
+	title := valueLabel(n.ID, opts)
+	if n.Type != "" {
+		title = string(n.Type) + " " + title
+	}
+	return title
+}
+
+// valueLabel writes a value without the N-Quads brackets and quotes.
+func valueLabel(v quad.Value, opts Options) string {
+	switch v := v.(type) {
+	case quad.IRI:
+		return opts.Shorten(v)
+	case quad.String:
+		return string(v)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v.Native())
+}
+
+// recordEscaper escapes characters that have a meaning in a record label.
+var recordEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`|`, `\|`,
+	`<`, `\<`,
+	`>`, `\>`,
+)
+
+// dotEscaper escapes characters of a quoted DOT string.
+var dotEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+)
+
+// WriteDOT writes the graph in the Graphviz DOT language. Nodes with
+// literal properties are drawn as records, one line per property, and are
+// colored by their rdf:type.
+func WriteDOT(w io.Writer, g *Graph, opts Options) error {
+	bw := bufio.NewWriter(w)
+
+	ids := g.IDs()
+
+	fmt.Fprintln(bw, "digraph cayley_graph {")
+	fmt.Fprintln(bw, `  node [fontname="Helvetica", style="filled"];`)
+	fmt.Fprintln(bw, `  edge [fontname="Helvetica"];`)
+	fmt.Fprintln(bw)
+
+	for _, n := range g.Nodes {
+		id := ids[n.ID]
+
+		style := StyleOf(n)
+		if len(n.Props) == 0 {
+			fmt.Fprintf(bw, "  %s [label=\"%s\", fillcolor=\"%s\"];\n",
+				id, dotEscaper.Replace(Title(n, opts)), style.Color)
+			continue
+		}
+
+		fields := []string{recordEscaper.Replace(Title(n, opts))}
+		var props []string
+		for _, p := range n.Props {
+			props = append(props, recordEscaper.Replace(
+				opts.Shorten(p.Name)+": "+valueLabel(p.Value, opts),
+			)+`\l`)
+		}
+		fields = append(fields, strings.Join(props, ""))
+
+		fmt.Fprintf(bw, "  %s [shape=\"record\", label=\"{%s}\", fillcolor=\"%s\"];\n",
+			id, strings.Join(fields, "|"), style.Color)
+	}
+
+	fmt.Fprintln(bw)
+
+	for _, e := range g.Edges {
+		fmt.Fprintf(bw, "  %s -> %s [label=\"%s\"];\n",
+			ids[e.From], ids[e.To], dotEscaper.Replace(opts.Shorten(e.Label)))
+	}
+
+	fmt.Fprintln(bw, "}")
+
+	return bw.Flush()
+}
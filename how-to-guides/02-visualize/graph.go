@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/voc/rdf"
+)
+
+// Prefix maps a long IRI namespace to a short prefix, ex: schema: for
+// http://schema.org/.
+type Prefix struct {
+	Short string
+	Full  string
+}
+
+// DefaultPrefixes are used when Options.Prefixes is nil.
+var DefaultPrefixes = []Prefix{
+	{Short: "schema:", Full: "http://schema.org/"},
+	{Short: "rdf:", Full: "http://www.w3.org/1999/02/22-rdf-syntax-ns#"},
+	{Short: "rdfs:", Full: "http://www.w3.org/2000/01/rdf-schema#"},
+}
+
+// Options control which part of the store is exported and how IRIs are
+// written. They are shared by all exporters.
+type Options struct {
+	// Root limits the export to the nodes reachable from this node by
+	// following edges forward, ex: a clinic, its opening hours and its admin.
+	// Everything is exported when Root is nil.
+	Root quad.Value
+
+	// Neighborhood limits the export to the nodes around a node, following
+	// edges in both directions. It takes precedence over Root.
+	Neighborhood *Neighborhood
+
+	// Types limits the export to nodes of these rdf:types. Nodes without a
+	// type are kept as long as an exported node points to them.
+	Types []quad.IRI
+
+	// Prefixes are used to shorten IRIs in labels.
+	Prefixes []Prefix
+}
+
+// Shorten replaces a known namespace of an IRI with its prefix.
+func (o Options) Shorten(iri quad.IRI) string {
+	prefixes := o.Prefixes
+	if prefixes == nil {
+		prefixes = DefaultPrefixes
+	}
+	s := string(iri)
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p.Full) {
+			return p.Short + s[len(p.Full):]
+		}
+	}
+	return s
+}
+
+// Prop is a literal property of a node, ex: name: "Healthy Life".
+type Prop struct {
+	Name  quad.IRI
+	Value quad.Value
+}
+
+// Node is a subject or an object of the graph. Literal properties are
+// folded into the node instead of becoming nodes of their own.
+type Node struct {
+	ID    quad.Value
+	Type  quad.IRI // value of rdf:type, empty for untyped nodes
+	Props []Prop
+}
+
+// Edge is a quad pointing from one node to another node.
+type Edge struct {
+	From  quad.Value
+	To    quad.Value
+	Label quad.IRI
+}
+
+// Graph is the shape shared by all exporters. Nodes and edges are sorted,
+// so the same store always produces the same output.
+type Graph struct {
+	Nodes []*Node
+	Edges []Edge
+
+	nodes map[quad.Value]*Node
+}
+
+// Node returns the node with the given id, or nil.
+func (g *Graph) Node(id quad.Value) *Node {
+	return g.nodes[id]
+}
+
+// IDs returns short ids for the nodes, ex: n0, n1, to use in formats that
+// can't use IRIs as ids.
+func (g *Graph) IDs() map[quad.Value]string {
+	ids := make(map[quad.Value]string, len(g.Nodes))
+	for i, n := range g.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+	}
+	return ids
+}
+
+func (g *Graph) node(id quad.Value) *Node {
+	n, ok := g.nodes[id]
+	if !ok {
+		n = &Node{ID: id}
+		g.nodes[id] = n
+	}
+	return n
+}
+
+// isNode tells if a value should become a node when it is an object.
+func isNode(v quad.Value) bool {
+	switch v.(type) {
+	case quad.IRI, quad.BNode:
+		return true
+	}
+	return false
+}
+
+// NewGraph folds quads into nodes and edges.
+func NewGraph(quads []quad.Quad, opts Options) *Graph {
+	g := &Graph{nodes: make(map[quad.Value]*Node)}
+
+	var edges []Edge
+	for _, q := range quads {
+		n := g.node(q.Subject)
+		p, _ := q.Predicate.(quad.IRI)
+
+		switch {
+		case p == quad.IRI(rdf.Type):
+			if t, ok := q.Object.(quad.IRI); ok {
+				n.Type = quad.IRI(opts.Shorten(t))
+			}
+		case isNode(q.Object):
+			g.node(q.Object)
+			edges = append(edges, Edge{From: q.Subject, To: q.Object, Label: p})
+		default:
+			n.Props = append(n.Props, Prop{Name: p, Value: q.Object})
+		}
+	}
+
+	keep := g.filter(edges, opts.Types)
+
+	for id, n := range g.nodes {
+		if !keep[id] {
+			delete(g.nodes, id)
+			continue
+		}
+		sort.SliceStable(n.Props, func(i, j int) bool {
+			return n.Props[i].Name < n.Props[j].Name
+		})
+		g.Nodes = append(g.Nodes, n)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].ID.String() < g.Nodes[j].ID.String()
+	})
+
+	for _, e := range edges {
+		if keep[e.From] && keep[e.To] {
+			g.Edges = append(g.Edges, e)
+		}
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return a.From.String() < b.From.String()
+		}
+		if a.Label != b.Label {
+			return a.Label < b.Label
+		}
+		return a.To.String() < b.To.String()
+	})
+
+	return g
+}
+
+// filter returns the ids of the nodes that pass the type filter.
+func (g *Graph) filter(edges []Edge, types []quad.IRI) map[quad.Value]bool {
+	keep := make(map[quad.Value]bool, len(g.nodes))
+	if len(types) == 0 {
+		for id := range g.nodes {
+			keep[id] = true
+		}
+		return keep
+	}
+
+	for id, n := range g.nodes {
+		for _, t := range types {
+			if n.Type == t {
+				keep[id] = true
+			}
+		}
+	}
+	// keep untyped nodes that a kept node points to, ex: schema:Monday
+	for _, e := range edges {
+		if keep[e.From] && g.nodes[e.To].Type == "" {
+			keep[e.To] = true
+		}
+	}
+	return keep
+}
+
+// LoadQuads reads the quads selected by the options from the store.
+func LoadQuads(store *cayley.Handle, opts Options) ([]quad.Quad, error) {
+	switch {
+	case opts.Neighborhood != nil:
+		return opts.Neighborhood.Quads(store)
+	case opts.Root != nil:
+		return reachableQuads(store, opts.Root)
+	}
+	return allQuads(store)
+}
+
+// LoadGraph reads quads from the store and folds them into a Graph.
+func LoadGraph(store *cayley.Handle, opts Options) (*Graph, error) {
+	quads, err := LoadQuads(store, opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewGraph(quads, opts), nil
+}
+
+func allQuads(store *cayley.Handle) ([]quad.Quad, error) {
+	it := store.QuadsAllIterator()
+	defer it.Close()
+
+	ctx := context.TODO()
+
+	var quads []quad.Quad
+	for it.Next(ctx) {
+		quads = append(quads, store.Quad(it.Result()))
+	}
+	return quads, it.Err()
+}
+
+// reachableQuads collects the quads of the root node and of every node it
+// points to, directly or not.
+func reachableQuads(store *cayley.Handle, root quad.Value) ([]quad.Quad, error) {
+	ctx := context.TODO()
+
+	var quads []quad.Quad
+	seen := map[quad.Value]bool{root: true}
+	queue := []quad.Value{root}
+
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		ref := store.ValueOf(id)
+		if ref == nil {
+			continue
+		}
+
+		it := store.QuadIterator(quad.Subject, ref)
+		for it.Next(ctx) {
+			q := store.Quad(it.Result())
+			quads = append(quads, q)
+
+			if q.Predicate != quad.IRI(rdf.Type) && isNode(q.Object) && !seen[q.Object] {
+				seen[q.Object] = true
+				queue = append(queue, q.Object)
+			}
+		}
+		err := it.Err()
+		it.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return quads, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// WriteGraphML writes the graph in the GraphML format, which can be opened
+// in Gephi. Every literal property becomes a node attribute, so it can be
+// used for filtering and coloring there.
+func WriteGraphML(w io.Writer, g *Graph, opts Options) error {
+	bw := bufio.NewWriter(w)
+
+	ids := g.IDs()
+
+	// one attribute per literal property, ex: name, schema:opens
+	keys := make(map[string]string)
+	var names []string
+	for _, n := range g.Nodes {
+		for _, p := range n.Props {
+			name := opts.Shorten(p.Name)
+			if _, ok := keys[name]; !ok {
+				keys[name] = ""
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		keys[name] = fmt.Sprintf("p%d", i)
+	}
+
+	fmt.Fprintln(bw, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(bw, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	fmt.Fprintln(bw, `  <key id="label" for="node" attr.name="label" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <key id="type" for="node" attr.name="type" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <key id="color" for="node" attr.name="color" attr.type="string"/>`)
+	for _, name := range names {
+		fmt.Fprintf(bw, "  <key id=\"%s\" for=\"node\" attr.name=\"%s\" attr.type=\"string\"/>\n",
+			keys[name], xmlEscape(name))
+	}
+	fmt.Fprintln(bw, `  <key id="predicate" for="edge" attr.name="label" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <graph id="cayley_graph" edgedefault="directed">`)
+
+	for _, n := range g.Nodes {
+		fmt.Fprintf(bw, "    <node id=\"%s\">\n", ids[n.ID])
+		fmt.Fprintf(bw, "      <data key=\"label\">%s</data>\n", xmlEscape(Title(n, opts)))
+		if n.Type != "" {
+			fmt.Fprintf(bw, "      <data key=\"type\">%s</data>\n", xmlEscape(string(n.Type)))
+		}
+		fmt.Fprintf(bw, "      <data key=\"color\">%s</data>\n", hex(colorOf(StyleOf(n).Color)))
+		for _, p := range n.Props {
+			fmt.Fprintf(bw, "      <data key=\"%s\">%s</data>\n",
+				keys[opts.Shorten(p.Name)], xmlEscape(valueLabel(p.Value, opts)))
+		}
+		fmt.Fprintln(bw, "    </node>")
+	}
+
+	for i, e := range g.Edges {
+		fmt.Fprintf(bw, "    <edge id=\"e%d\" source=\"%s\" target=\"%s\">\n", i, ids[e.From], ids[e.To])
+		fmt.Fprintf(bw, "      <data key=\"predicate\">%s</data>\n", xmlEscape(opts.Shorten(e.Label)))
+		fmt.Fprintln(bw, "    </edge>")
+	}
+
+	fmt.Fprintln(bw, "  </graph>")
+	fmt.Fprintln(bw, "</graphml>")
+
+	return bw.Flush()
+}
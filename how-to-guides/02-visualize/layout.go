@@ -0,0 +1,291 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/cayleygraph/cayley/quad"
+)
+
+// Text metrics shared by the SVG and the PNG renderer. They match
+// basicfont.Face7x13, which is used to draw text into PNG images.
+const (
+	charWidth  = 7
+	lineHeight = 15
+	padding    = 6
+	margin     = 20
+	layerGap   = 70
+	nodeGap    = 30
+)
+
+// colors maps the Graphviz color names used by the styles to RGB, so the
+// renderers draw the same colors as dot does.
+var colors = map[string]color.RGBA{
+	"green":     {0x00, 0xff, 0x00, 0xff},
+	"lightblue": {0xad, 0xd8, 0xe6, 0xff},
+	"khaki":     {0xf0, 0xe6, 0x8c, 0xff},
+	"lightgrey": {0xd3, 0xd3, 0xd3, 0xff},
+	"white":     {0xff, 0xff, 0xff, 0xff},
+	"black":     {0x00, 0x00, 0x00, 0xff},
+}
+
+func colorOf(name string) color.RGBA {
+	if c, ok := colors[name]; ok {
+		return c
+	}
+	return colors["white"]
+}
+
+// Box is a node placed on the canvas. X and Y are its top left corner.
+type Box struct {
+	Node   *Node
+	Title  string
+	Lines  []string // literal properties, one per line
+	Fill   color.RGBA
+	X, Y   float64
+	W, H   float64
+	layer  int
+	order  float64
+	inputs []*Box
+}
+
+// Center returns the center of the box.
+func (b *Box) Center() (float64, float64) {
+	return b.X + b.W/2, b.Y + b.H/2
+}
+
+// clip returns the point where the line from the center of the box
+// towards (x, y) leaves the box.
+func (b *Box) clip(x, y float64) (float64, float64) {
+	cx, cy := b.Center()
+	dx, dy := x-cx, y-cy
+	if dx == 0 && dy == 0 {
+		return cx, cy
+	}
+	t := math.Inf(1)
+	if dx != 0 {
+		t = math.Min(t, b.W/2/math.Abs(dx))
+	}
+	if dy != 0 {
+		t = math.Min(t, b.H/2/math.Abs(dy))
+	}
+	return cx + t*dx, cy + t*dy
+}
+
+// Arrow is an edge placed on the canvas, from (X1, Y1) to (X2, Y2).
+type Arrow struct {
+	Label          string
+	X1, Y1, X2, Y2 float64
+}
+
+// Layout is a graph with coordinates, ready to be drawn.
+type Layout struct {
+	Boxes  []*Box
+	Arrows []Arrow
+	Width  float64
+	Height float64
+}
+
+// Layered places the nodes of a graph in layers, top to bottom, so edges
+// mostly point down: a clinic is above its admin and its opening hours. The
+// result only depends on the graph, which makes the rendered files stable
+// enough to compare with golden copies, see render_test.go.
+func Layered(g *Graph, opts Options) *Layout {
+	l := &Layout{}
+	boxes := make(map[quad.Value]*Box, len(g.Nodes))
+
+	for _, n := range g.Nodes {
+		b := &Box{
+			Node:  n,
+			Title: Title(n, opts),
+			Fill:  colorOf(StyleOf(n).Color),
+		}
+		for _, p := range n.Props {
+			b.Lines = append(b.Lines, opts.Shorten(p.Name)+": "+valueLabel(p.Value, opts))
+		}
+
+		longest := len(b.Title)
+		for _, s := range b.Lines {
+			if len(s) > longest {
+				longest = len(s)
+			}
+		}
+		b.W = float64(longest*charWidth + 2*padding)
+		b.H = float64((1+len(b.Lines))*lineHeight + 2*padding)
+
+		boxes[n.ID] = b
+		l.Boxes = append(l.Boxes, b)
+	}
+
+	edges := acyclic(g, boxes)
+	for _, e := range edges {
+		boxes[e.To].inputs = append(boxes[e.To].inputs, boxes[e.From])
+	}
+
+	layers := assignLayers(l.Boxes)
+	orderLayers(layers)
+	l.place(layers)
+
+	for _, e := range g.Edges {
+		from, to := boxes[e.From], boxes[e.To]
+		tx, ty := to.Center()
+		fx, fy := from.Center()
+		x1, y1 := from.clip(tx, ty)
+		x2, y2 := to.clip(fx, fy)
+		l.Arrows = append(l.Arrows, Arrow{
+			Label: opts.Shorten(e.Label),
+			X1:    x1, Y1: y1, X2: x2, Y2: y2,
+		})
+	}
+
+	return l
+}
+
+// acyclic returns the edges of the graph without the ones that close a
+// cycle, found by a depth first search.
+func acyclic(g *Graph, boxes map[quad.Value]*Box) []Edge {
+	out := make(map[quad.Value][]Edge)
+	hasInput := make(map[quad.Value]bool)
+	for _, e := range g.Edges {
+		if e.From != e.To {
+			out[e.From] = append(out[e.From], e)
+			hasInput[e.To] = true
+		}
+	}
+
+	const (
+		unseen = iota
+		active
+		done
+	)
+	state := make(map[quad.Value]int, len(boxes))
+
+	var edges []Edge
+	var visit func(id quad.Value)
+	visit = func(id quad.Value) {
+		state[id] = active
+		for _, e := range out[id] {
+			switch state[e.To] {
+			case unseen:
+				edges = append(edges, e)
+				visit(e.To)
+			case done:
+				edges = append(edges, e)
+			}
+		}
+		state[id] = done
+	}
+	// start from the nodes nothing points to, so they end up on top
+	for _, n := range g.Nodes {
+		if !hasInput[n.ID] {
+			visit(n.ID)
+		}
+	}
+	for _, n := range g.Nodes {
+		if state[n.ID] == unseen {
+			visit(n.ID)
+		}
+	}
+	return edges
+}
+
+// assignLayers puts every box one layer below the lowest box pointing to it.
+func assignLayers(all []*Box) [][]*Box {
+	layer := make(map[*Box]int, len(all))
+
+	var depth func(b *Box) int
+	depth = func(b *Box) int {
+		if d, ok := layer[b]; ok {
+			return d
+		}
+		d := 0
+		for _, in := range b.inputs {
+			if x := depth(in) + 1; x > d {
+				d = x
+			}
+		}
+		layer[b] = d
+		return d
+	}
+
+	var layers [][]*Box
+	for _, b := range all {
+		b.layer = depth(b)
+		for len(layers) <= b.layer {
+			layers = append(layers, nil)
+		}
+		layers[b.layer] = append(layers[b.layer], b)
+	}
+	return layers
+}
+
+// orderLayers reduces edge crossings by moving every box towards the
+// average position of the boxes pointing to it.
+func orderLayers(layers [][]*Box) {
+	if len(layers) < 2 {
+		return
+	}
+	for _, layer := range layers {
+		for i, b := range layer {
+			b.order = float64(i)
+		}
+	}
+
+	for sweep := 0; sweep < 4; sweep++ {
+		for _, layer := range layers[1:] {
+			for _, b := range layer {
+				if len(b.inputs) == 0 {
+					continue
+				}
+				sum := 0.0
+				for _, in := range b.inputs {
+					sum += in.order
+				}
+				b.order = sum / float64(len(b.inputs))
+			}
+			sort.SliceStable(layer, func(i, j int) bool {
+				return layer[i].order < layer[j].order
+			})
+			for i, b := range layer {
+				b.order = float64(i)
+			}
+		}
+	}
+}
+
+// place gives coordinates to the boxes, centering every layer.
+func (l *Layout) place(layers [][]*Box) {
+	widths := make([]float64, len(layers))
+	for i, layer := range layers {
+		for j, b := range layer {
+			if j > 0 {
+				widths[i] += nodeGap
+			}
+			widths[i] += b.W
+		}
+		if widths[i] > l.Width {
+			l.Width = widths[i]
+		}
+	}
+
+	y := float64(margin)
+	for i, layer := range layers {
+		x := margin + (l.Width-widths[i])/2
+		tallest := 0.0
+		for _, b := range layer {
+			b.X, b.Y = x, y
+			x += b.W + nodeGap
+			if b.H > tallest {
+				tallest = b.H
+			}
+		}
+		y += tallest + layerGap
+	}
+
+	l.Width += 2 * margin
+	l.Height = y - layerGap + margin
+	if len(layers) == 0 {
+		l.Height = 2 * margin
+	}
+}
@@ -1,15 +1,21 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"log"
-	"context"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/cayleygraph/cayley"
 	"github.com/cayleygraph/cayley/graph"
 	_ "github.com/cayleygraph/cayley/graph/kv/bolt"
 	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/quad/nquads"
 	"github.com/cayleygraph/cayley/schema"
+	"github.com/cayleygraph/cayley/voc/rdf"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -22,45 +28,121 @@ type Admin struct {
 }
 
 type Clinic struct {
-	Name      string   `json:"name" quad:"name"`
-	Address1  string   `json:"address" quad:"address"`
-	CreatedBy quad.IRI `quad:"createdBy"`
+	Name      string         `json:"name" quad:"name"`
+	Address1  string         `json:"address" quad:"address"`
+	CreatedBy quad.IRI       `quad:"createdBy"`
+	Hours     []OpeningHours `quad:"schema:openingHoursSpecification"`
+}
+
+type OpeningHours struct {
+	DayOfWeek quad.IRI `json:"day" quad:"schema:dayOfWeek"`
+	Slot      int      `json:"slot" quad:"slot"`
+	Opens     string   `json:"opens" quad:"schema:opens"` // ex: 12:00 or 12:00:00
+	Closes    string   `json:"closes" quad:"schema:closes"`
 }
 
 func init() {
 	schema.RegisterType("Admin", Admin{})
 	schema.RegisterType("Clinic", Clinic{})
+	schema.RegisterType("schema:OpeningHoursSpecification", OpeningHours{})
 	schema.GenerateID = func(_ interface{}) quad.Value {
 		return quad.IRI(uuid.NewV1().String())
 	}
 }
 
 func main() {
-	store := initializeAndOpenGraph(dbPath)
-	a := Admin{
-		Name:           "Josh",
-		Email:          "josh_f@gmail.com",
-		HashedPassword: "435iue8uou9eu",
+	db := flag.String("db", "", "bolt database to export; a new one with sample data is created when empty")
+	out := flag.String("o", "graph.dot", "output file, - for stdout")
+	format := flag.String("format", "", "dot, svg, png, mermaid, graphml, cytoscape or nquads; taken from the extension of -o when empty")
+	clinic := flag.String("clinic", "", "export only the clinic with this name and what it points to")
+	types := flag.String("types", "", "comma separated rdf:types to export, ex: Clinic,Admin")
+	around := flag.String("around", "", "export only the neighborhood of the node with this IRI")
+	hops := flag.Int("hops", 1, "size of the neighborhood, in edges")
+	allow := flag.String("allow", "", "comma separated predicates to follow in the neighborhood, all when empty")
+	deny := flag.String("deny", "", "comma separated predicates to leave out of the neighborhood")
+	maxNodes := flag.Int("max-nodes", 0, "maximum number of nodes in the neighborhood, 0 for no limit")
+	flag.Parse()
+
+	var store *cayley.Handle
+	if *db == "" {
+		os.RemoveAll(dbPath)
+		store = initializeAndOpenGraph(dbPath)
+		insertSampleData(store)
+	} else {
+		store = initializeAndOpenGraph(*db)
 	}
+	defer store.Close()
 
-	err := insert(store, a)
-	checkErr(err)
+	var opts Options
+	if *clinic != "" {
+		id, err := findClinicID(store, *clinic)
+		checkErr(err)
+		opts.Root = id
+	}
+	opts.Types = splitIRIs(*types)
+	if *around != "" {
+		opts.Neighborhood = &Neighborhood{
+			Root:     quad.IRI(*around),
+			Hops:     *hops,
+			Allow:    splitIRIs(*allow),
+			Deny:     splitIRIs(*deny),
+			MaxNodes: *maxNodes,
+		}
+	}
 
-	adminId, err := findAdminID(store, a.Email)
+	quads, err := LoadQuads(store, opts)
 	checkErr(err)
 
-	c := Clinic{
-		Name:      "Healthy Life",
-		Address1:  "11 boar st, Singapore 11233",
-		CreatedBy: adminId,
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		checkErr(err)
+		defer f.Close()
+		w = f
 	}
 
-	err = insert(store, c)
-	checkErr(err)
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(*out), ".")
+	}
+	checkErr(writeGraph(w, *format, quads, opts))
+}
+
+// splitIRIs parses a comma separated list of IRIs.
+func splitIRIs(list string) []quad.IRI {
+	var iris []quad.IRI
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			iris = append(iris, quad.IRI(s))
+		}
+	}
+	return iris
+}
+
+func writeGraph(w io.Writer, format string, quads []quad.Quad, opts Options) error {
+	if format == "nquads" || format == "nq" {
+		qw := nquads.NewWriter(w)
+		if _, err := qw.WriteQuads(quads); err != nil {
+			return err
+		}
+		return qw.Close()
+	}
 
-	printAdmins(store)
-	printClinics(store)
-	printQuads(store)
+	g := NewGraph(quads, opts)
+	switch format {
+	case "dot", "":
+		return WriteDOT(w, g, opts)
+	case "svg":
+		return WriteSVG(w, Layered(g, opts))
+	case "png":
+		return WritePNG(w, Layered(g, opts))
+	case "mermaid", "mmd":
+		return WriteMermaid(w, g, opts)
+	case "graphml":
+		return WriteGraphML(w, g, opts)
+	case "cytoscape", "json":
+		return WriteCytoscape(w, g, opts)
+	}
+	return fmt.Errorf("unknown format %q", format)
 }
 
 func checkErr(err error) {
@@ -81,10 +163,43 @@ func initializeAndOpenGraph(dbFile string) *cayley.Handle {
 	return store
 }
 
+// insertSampleData creates the admin and the clinic of the first guide,
+// this time with opening hours.
+func insertSampleData(store *cayley.Handle) {
+	a := Admin{
+		Name:           "Josh",
+		Email:          "josh_f@gmail.com",
+		HashedPassword: "435iue8uou9eu",
+	}
+
+	err := insert(store, a)
+	checkErr(err)
+
+	adminId, err := findAdminID(store, a.Email)
+	checkErr(err)
+
+	c := Clinic{
+		Name:      "Healthy Life",
+		Address1:  "11 boar st, Singapore 11233",
+		CreatedBy: adminId,
+		Hours: []OpeningHours{
+			{DayOfWeek: quad.IRI("schema:Monday"), Slot: 1, Opens: "08:00", Closes: "12:00"},
+			{DayOfWeek: quad.IRI("schema:Monday"), Slot: 2, Opens: "13:00", Closes: "18:00"},
+		},
+	}
+
+	err = insert(store, c)
+	checkErr(err)
+}
+
 func insert(h *cayley.Handle, o interface{}) error {
 	qw := graph.NewWriter(h)
-	defer qw.Close() // don't forget to close a writer; it has some internal buffering
 	_, err := schema.WriteAsQuads(qw, o)
+	// don't forget to close a writer; it has some internal buffering, and
+	// writing what is left of it can fail too
+	if cerr := qw.Close(); err == nil {
+		err = cerr
+	}
 	return err
 }
 
@@ -99,52 +214,18 @@ func findAdminID(store *cayley.Handle, email string) (quad.IRI, error) {
 	return id.(quad.IRI), nil
 }
 
-func printQuads(store *cayley.Handle) {
-	// get all quads
-	it := store.QuadsAllIterator()
-	defer it.Close()
-
-	fmt.Println("Quads:")
-	fmt.Println("-----")
-
-	ctx := context.TODO()
-
-	for it.Next(ctx) {
-		fmt.Println(store.Quad(it.Result()))
-	}
-
-	fmt.Println()
-}
-
-func printAdmins(store *cayley.Handle) {
-	// get all admins
-	var admins []Admin
-	checkErr(schema.LoadTo(nil, store, &admins))
-
-	fmt.Println("Admins:")
-	fmt.Println("------")
+func findClinicID(store *cayley.Handle, name string) (quad.Value, error) {
+	p := cayley.StartPath(store).
+		Has(quad.IRI(rdf.Type), quad.IRI("Clinic")).
+		Has(quad.IRI("name"), quad.String(name))
+	id, err := p.Iterate(nil).FirstValue(nil)
 
-	for _, a := range admins {
-		fmt.Println("Name:", a.Name)
-		fmt.Println("Email:", a.Email)
-		fmt.Println("Hashed Password:", a.HashedPassword)
+	if err != nil {
+		return nil, err
 	}
-
-	fmt.Println()
-}
-
-func printClinics(store *cayley.Handle) {
-	// get all admins
-	var clinics []Clinic
-	checkErr(schema.LoadTo(nil, store, &clinics))
-
-	fmt.Println("Clinics:")
-	fmt.Println("-------")
-
-	for _, c := range clinics {
-		fmt.Println("Name:", c.Name)
-		fmt.Println("Email:", c.Address1)
+	if id == nil {
+		return nil, fmt.Errorf("clinic %q not found", name)
 	}
 
-	fmt.Println()
+	return id, nil
 }
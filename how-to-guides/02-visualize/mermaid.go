@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/cayleygraph/cayley/quad"
+)
+
+// mermaidEscaper replaces characters that end a quoted Mermaid label with
+// entity codes.
+var mermaidEscaper = strings.NewReplacer(
+	`"`, "#quot;",
+	`<`, "#lt;",
+	`>`, "#gt;",
+)
+
+// className turns a type into a Mermaid class name,
+// ex: schema:OpeningHoursSpecification -> schema_OpeningHoursSpecification.
+func className(t quad.IRI) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, string(t))
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart, which can be pasted
+// into Markdown documents. Nodes are colored by their rdf:type like in
+// WriteDOT.
+func WriteMermaid(w io.Writer, g *Graph, opts Options) error {
+	bw := bufio.NewWriter(w)
+
+	ids := g.IDs()
+	classes := make(map[quad.IRI][]string)
+
+	fmt.Fprintln(bw, "flowchart TD")
+
+	for _, n := range g.Nodes {
+		lines := []string{"<b>" + mermaidEscaper.Replace(Title(n, opts)) + "</b>"}
+		for _, p := range n.Props {
+			lines = append(lines, mermaidEscaper.Replace(opts.Shorten(p.Name)+": "+valueLabel(p.Value, opts)))
+		}
+		fmt.Fprintf(bw, "  %s[\"%s\"]\n", ids[n.ID], strings.Join(lines, "<br/>"))
+
+		classes[n.Type] = append(classes[n.Type], ids[n.ID])
+	}
+
+	for _, e := range g.Edges {
+		fmt.Fprintf(bw, "  %s -->|\"%s\"| %s\n",
+			ids[e.From], mermaidEscaper.Replace(opts.Shorten(e.Label)), ids[e.To])
+	}
+
+	var types []quad.IRI
+	for t := range classes {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	for _, t := range types {
+		name := className(t)
+		if t == "" {
+			name = "untyped"
+		}
+		fill := colorOf(TypeStyle(t).Color)
+		fmt.Fprintf(bw, "  classDef %s fill:%s,stroke:#000000\n", name, hex(fill))
+		fmt.Fprintf(bw, "  class %s %s\n", strings.Join(classes[t], ","), name)
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/voc/rdf"
+)
+
+// Neighborhood describes the part of the graph around one node: the nodes
+// at most Hops edges away from Root, following edges in both directions.
+type Neighborhood struct {
+	Root quad.Value
+	Hops int
+
+	// Allow lists the predicates to follow, all of them when empty.
+	Allow []quad.IRI
+	// Deny lists predicates to leave out, ex: hashed_password.
+	Deny []quad.IRI
+
+	// MaxNodes stops the walk after that many nodes, 0 means no limit.
+	MaxNodes int
+}
+
+func (nb *Neighborhood) follows(p quad.Value) bool {
+	for _, d := range nb.Deny {
+		if p == d {
+			return false
+		}
+	}
+	if len(nb.Allow) == 0 {
+		return true
+	}
+	for _, a := range nb.Allow {
+		if p == a {
+			return true
+		}
+	}
+	return false
+}
+
+// Quads returns the quads of the neighborhood: the literal properties and
+// types of every node in it and the edges between those nodes.
+//
+// rdf:type is never followed, otherwise every clinic would be one hop away
+// from every other clinic through the Clinic node.
+func (nb *Neighborhood) Quads(store graph.QuadStore) ([]quad.Quad, error) {
+	ctx := context.TODO()
+
+	type entry struct {
+		id    quad.Value
+		depth int
+	}
+
+	var (
+		quads []quad.Quad
+		edges []quad.Quad
+	)
+	seen := map[quad.Value]bool{nb.Root: true}
+	queue := []entry{{id: nb.Root}}
+
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		ref := store.ValueOf(cur.id)
+		if ref == nil {
+			continue
+		}
+
+		for _, dir := range []quad.Direction{quad.Subject, quad.Object} {
+			it := store.QuadIterator(dir, ref)
+			for it.Next(ctx) {
+				q := store.Quad(it.Result())
+				if !nb.follows(q.Predicate) {
+					continue
+				}
+
+				if dir == quad.Subject && (q.Predicate == quad.IRI(rdf.Type) || !isNode(q.Object)) {
+					quads = append(quads, q)
+					continue
+				}
+				if q.Predicate == quad.IRI(rdf.Type) {
+					continue
+				}
+
+				edges = append(edges, q)
+
+				next := q.Object
+				if dir == quad.Object {
+					next = q.Subject
+				}
+				if seen[next] || cur.depth >= nb.Hops {
+					continue
+				}
+				if nb.MaxNodes > 0 && len(seen) >= nb.MaxNodes {
+					continue
+				}
+				seen[next] = true
+				queue = append(queue, entry{id: next, depth: cur.depth + 1})
+			}
+			err := it.Err()
+			it.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// keep the edges between nodes of the neighborhood, once: an edge
+	// between two of them is found from both ends
+	added := make(map[quad.Quad]bool)
+	for _, q := range edges {
+		if seen[q.Subject] && seen[q.Object] && !added[q] {
+			added[q] = true
+			quads = append(quads, q)
+		}
+	}
+
+	return quads, nil
+}
+
+// CopyTo writes the quads of the neighborhood to any quad writer, ex: an
+// N-Quads file or another store.
+func (nb *Neighborhood) CopyTo(w quad.Writer, store graph.QuadStore) (int, error) {
+	quads, err := nb.Quads(store)
+	if err != nil {
+		return 0, err
+	}
+	return w.WriteQuads(quads)
+}
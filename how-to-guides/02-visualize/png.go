@@ -0,0 +1,151 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+var (
+	black     = colors["black"]
+	labelGrey = color.RGBA{0x55, 0x55, 0x55, 0xff}
+)
+
+// WritePNG draws a layout as a PNG image, without any external tools.
+func WritePNG(w io.Writer, l *Layout) error {
+	img := image.NewRGBA(image.Rect(0, 0, int(math.Ceil(l.Width)), int(math.Ceil(l.Height))))
+	draw.Draw(img, img.Bounds(), image.NewUniform(colors["white"]), image.Point{}, draw.Src)
+
+	for _, a := range l.Arrows {
+		drawLine(img, a.X1, a.Y1, a.X2, a.Y2, black)
+		drawArrowHead(img, a.X1, a.Y1, a.X2, a.Y2, black)
+	}
+
+	for _, b := range l.Boxes {
+		r := image.Rect(int(b.X), int(b.Y), int(b.X+b.W), int(b.Y+b.H))
+		draw.Draw(img, r, image.NewUniform(b.Fill), image.Point{}, draw.Src)
+		drawRect(img, r, black)
+
+		y := b.Y + padding + lineHeight - 3
+		drawText(img, b.X+padding, y, b.Title, black)
+
+		if len(b.Lines) == 0 {
+			continue
+		}
+		sep := b.Y + padding + lineHeight + 1
+		drawLine(img, b.X, sep, b.X+b.W, sep, black)
+		for _, s := range b.Lines {
+			y += lineHeight
+			drawText(img, b.X+padding, y, s, black)
+		}
+	}
+
+	for _, a := range l.Arrows {
+		x := (a.X1+a.X2)/2 - float64(len(a.Label)*charWidth)/2
+		drawText(img, x, (a.Y1+a.Y2)/2, a.Label, labelGrey)
+	}
+
+	return png.Encode(w, img)
+}
+
+func drawText(img *image.RGBA, x, y float64, s string, c color.Color) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(int(x), int(y)),
+	}
+	d.DrawString(s)
+}
+
+func drawRect(img *image.RGBA, r image.Rectangle, c color.Color) {
+	x0, y0 := float64(r.Min.X), float64(r.Min.Y)
+	x1, y1 := float64(r.Max.X-1), float64(r.Max.Y-1)
+	drawLine(img, x0, y0, x1, y0, c)
+	drawLine(img, x0, y1, x1, y1, c)
+	drawLine(img, x0, y0, x0, y1, c)
+	drawLine(img, x1, y0, x1, y1, c)
+}
+
+// drawLine draws a one pixel wide line using Bresenham's algorithm.
+func drawLine(img *image.RGBA, fx0, fy0, fx1, fy1 float64, c color.Color) {
+	x0, y0 := int(math.Round(fx0)), int(math.Round(fy0))
+	x1, y1 := int(math.Round(fx1)), int(math.Round(fy1))
+
+	dx, sx := abs(x1-x0), 1
+	if x0 > x1 {
+		sx = -1
+	}
+	dy, sy := -abs(y1-y0), 1
+	if y0 > y1 {
+		sy = -1
+	}
+
+	e := dx + dy
+	for {
+		img.Set(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+// drawArrowHead fills a triangle at the end of the line from (x0, y0) to
+// (x1, y1).
+func drawArrowHead(img *image.RGBA, x0, y0, x1, y1 float64, c color.Color) {
+	const length, width = 10, 4
+
+	dx, dy := x1-x0, y1-y0
+	n := math.Hypot(dx, dy)
+	if n == 0 {
+		return
+	}
+	dx, dy = dx/n, dy/n
+
+	// the corners of the triangle
+	bx, by := x1-dx*length, y1-dy*length
+	ax, ay := bx-dy*width, by+dx*width
+	cx, cy := bx+dy*width, by-dx*width
+
+	minX, maxX := math.Floor(math.Min(x1, math.Min(ax, cx))), math.Ceil(math.Max(x1, math.Max(ax, cx)))
+	minY, maxY := math.Floor(math.Min(y1, math.Min(ay, cy))), math.Ceil(math.Max(y1, math.Max(ay, cy)))
+
+	side := func(px, py, qx, qy, rx, ry float64) float64 {
+		return (px-rx)*(qy-ry) - (qx-rx)*(py-ry)
+	}
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			d1 := side(x, y, x1, y1, ax, ay)
+			d2 := side(x, y, ax, ay, cx, cy)
+			d3 := side(x, y, cx, cy, x1, y1)
+			neg := d1 < 0 || d2 < 0 || d3 < 0
+			pos := d1 > 0 || d2 > 0 || d3 > 0
+			if !(neg && pos) {
+				img.Set(int(x), int(y), c)
+			}
+		}
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/voc/rdf"
+)
+
+var update = flag.Bool("update", false, "write the golden files in testdata instead of comparing with them")
+
+// sampleQuads are the admin, clinic and opening hours of the first guides,
+// with fixed IDs so the output doesn't change from one run to the next.
+func sampleQuads() []quad.Quad {
+	admin := quad.IRI("831bc569-43eb-11e7-9cd0-843a4b0f5a10")
+	clinic := quad.IRI("831c71de-43eb-11e7-9cd0-843a4b0f5a10")
+	hours := quad.IRI("831c9a2f-43eb-11e7-9cd0-843a4b0f5a10")
+	q := func(s quad.IRI, p string, o quad.Value) quad.Quad {
+		return quad.Quad{Subject: s, Predicate: quad.IRI(p), Object: o}
+	}
+	return []quad.Quad{
+		q(admin, rdf.Type, quad.IRI("Admin")),
+		q(admin, "name", quad.String("Josh")),
+		q(admin, "email", quad.String("josh_f@gmail.com")),
+		q(clinic, rdf.Type, quad.IRI("Clinic")),
+		q(clinic, "name", quad.String("Healthy Life")),
+		q(clinic, "address", quad.String("11 boar st, Singapore 11233")),
+		q(clinic, "createdBy", admin),
+		q(clinic, "schema:openingHoursSpecification", hours),
+		q(hours, rdf.Type, quad.IRI("schema:OpeningHoursSpecification")),
+		q(hours, "schema:dayOfWeek", quad.IRI("schema:Monday")),
+		q(hours, "schema:opens", quad.String("08:00")),
+		q(hours, "schema:closes", quad.String("12:00")),
+	}
+}
+
+// writeLayout writes the boxes and arrows of a layout as text, so a change
+// of layout shows in a diff, not only as different pixels.
+func writeLayout(w io.Writer, l *Layout) error {
+	fmt.Fprintf(w, "size %.1f %.1f\n", l.Width, l.Height)
+	for _, b := range l.Boxes {
+		fmt.Fprintf(w, "box %q %.1f %.1f %.1f %.1f %s\n", b.Title, b.X, b.Y, b.W, b.H, hex(b.Fill))
+		for _, s := range b.Lines {
+			fmt.Fprintf(w, "  %q\n", s)
+		}
+	}
+	for _, a := range l.Arrows {
+		_, err := fmt.Fprintf(w, "arrow %q %.1f %.1f %.1f %.1f\n", a.Label, a.X1, a.Y1, a.X2, a.Y2)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRender(t *testing.T) {
+	var opts Options
+	l := Layered(NewGraph(sampleQuads(), opts), opts)
+
+	for _, c := range []struct {
+		file  string
+		write func(io.Writer, *Layout) error
+	}{
+		{"sample.layout", writeLayout},
+		{"sample.svg", WriteSVG},
+		{"sample.png", WritePNG},
+	} {
+		t.Run(c.file, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := c.write(&buf, l); err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", c.file)
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v; run go test -update to create it", err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("output differs from %s; check it and run go test -update if the change is expected", golden)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+)
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// WriteSVG draws a layout as an SVG image.
+func WriteSVG(w io.Writer, l *Layout) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" font-family="monospace" font-size="12">`+"\n",
+		l.Width, l.Height, l.Width, l.Height)
+	fmt.Fprintln(bw, `  <defs>`)
+	fmt.Fprintln(bw, `    <marker id="arrow" markerWidth="10" markerHeight="8" refX="10" refY="4" orient="auto">`)
+	fmt.Fprintln(bw, `      <polygon points="0 0, 10 4, 0 8"/>`)
+	fmt.Fprintln(bw, `    </marker>`)
+	fmt.Fprintln(bw, `  </defs>`)
+	fmt.Fprintln(bw, `  <rect width="100%" height="100%" fill="#ffffff"/>`)
+
+	for _, a := range l.Arrows {
+		fmt.Fprintf(bw, `  <line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#000000" marker-end="url(#arrow)"/>`+"\n",
+			a.X1, a.Y1, a.X2, a.Y2)
+	}
+
+	for _, b := range l.Boxes {
+		fmt.Fprintf(bw, `  <rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="4" fill="%s" stroke="#000000"/>`+"\n",
+			b.X, b.Y, b.W, b.H, hex(b.Fill))
+
+		y := b.Y + padding + lineHeight - 3
+		fmt.Fprintf(bw, `  <text x="%.1f" y="%.1f" font-weight="bold">%s</text>`+"\n",
+			b.X+padding, y, html.EscapeString(b.Title))
+
+		if len(b.Lines) == 0 {
+			continue
+		}
+		sep := b.Y + padding + lineHeight + 1
+		fmt.Fprintf(bw, `  <line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#000000"/>`+"\n",
+			b.X, sep, b.X+b.W, sep)
+		for _, s := range b.Lines {
+			y += lineHeight
+			fmt.Fprintf(bw, `  <text x="%.1f" y="%.1f">%s</text>`+"\n",
+				b.X+padding, y, html.EscapeString(s))
+		}
+	}
+
+	// labels go last, so boxes do not hide them
+	for _, a := range l.Arrows {
+		fmt.Fprintf(bw, `  <text x="%.1f" y="%.1f" text-anchor="middle" fill="#555555">%s</text>`+"\n",
+			(a.X1+a.X2)/2, (a.Y1+a.Y2)/2, html.EscapeString(a.Label))
+	}
+
+	fmt.Fprintln(bw, `</svg>`)
+
+	return bw.Flush()
+}
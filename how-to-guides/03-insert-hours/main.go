@@ -116,8 +116,12 @@ func initializeAndOpenGraph(dbFile string) *cayley.Handle {
 
 func insert(h *cayley.Handle, o interface{}) error {
 	qw := graph.NewWriter(h)
-	defer qw.Close() // don't forget to close a writer; it has some internal buffering
 	_, err := schema.WriteAsQuads(qw, o)
+	// don't forget to close a writer; it has some internal buffering, and
+	// writing what is left of it can fail too
+	if cerr := qw.Close(); err == nil {
+		err = cerr
+	}
 	return err
 }
 
@@ -134,8 +134,12 @@ func loadJSON(JSONFile string) *Clinic {
 
 func insert(h *cayley.Handle, o interface{}) (quad.Value, error) {
 	qw := graph.NewWriter(h)
-	defer qw.Close() // don't forget to close a writer; it has some internal buffering
 	id, err := schema.WriteAsQuads(qw, o)
+	// don't forget to close a writer; it has some internal buffering, and
+	// writing what is left of it can fail too
+	if cerr := qw.Close(); err == nil {
+		err = cerr
+	}
 	return id, err
 }
 
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"context"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	_ "github.com/cayleygraph/cayley/graph/kv/bolt"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	uuid "github.com/satori/go.uuid"
+)
+
+var dbPath = "db.boltdb"
+
+type Admin struct {
+	Name           string `json:"name" quad:"name"`
+	Email          string `json:"email" quad:"email"`
+	HashedPassword string `json:"hashedPassword"  quad:"hashed_password"`
+}
+
+type Clinic struct {
+	Name      string         `json:"name" quad:"name"`
+	Address1  string         `json:"address" quad:"address"`
+	CreatedBy quad.IRI       `quad:"createdBy"`
+	OfficeTel string         `json:"officeTel" quad:"officeTel"`
+	Hours     []OpeningHours `quad:"schema:openingHoursSpecification"`
+}
+
+type OpeningHours struct {
+	DayOfWeek quad.IRI `json:"day" quad:"schema:dayOfWeek"`
+	Slot      int      `json:"slot" quad:"slot"`
+	Opens     string   `json:"opens" quad:"schema:opens"` // ex: 12:00 or 12:00:00
+	Closes    string   `json:"closes" quad:"schema:closes"`
+}
+
+func init() {
+	schema.RegisterType("Admin", Admin{})
+	schema.RegisterType("Clinic", Clinic{})
+	schema.RegisterType("schema:OpeningHoursSpecification", OpeningHours{})
+	schema.GenerateID = func(_ interface{}) quad.Value {
+		return quad.IRI(uuid.NewV1().String())
+	}
+}
+
+func main() {
+	os.RemoveAll(dbPath)
+	store := initializeAndOpenGraph(dbPath)
+	ctx := context.TODO()
+
+	acme := NewTenant(store, "acme")
+	globex := NewTenant(store, "globex")
+
+	// both organizations have an admin with the same email
+	for _, t := range []*Tenant{acme, globex} {
+		_, err := t.Insert(Admin{
+			Name:           "Josh",
+			Email:          "josh_f@gmail.com",
+			HashedPassword: "435iue8uou9eu",
+		})
+		checkErr(err)
+	}
+
+	acmeAdmin, err := acme.FindAdminID(ctx, "josh_f@gmail.com")
+	checkErr(err)
+	globexAdmin, err := globex.FindAdminID(ctx, "josh_f@gmail.com")
+	checkErr(err)
+
+	c := Clinic{
+		Name:      "Healthy Life",
+		Address1:  "11 boar st, Singapore 11233",
+		CreatedBy: acmeAdmin,
+		OfficeTel: "65 6100 0939",
+		Hours: []OpeningHours{
+			{DayOfWeek: quad.IRI("schema:Monday"), Slot: 1, Opens: "08:00", Closes: "12:00"},
+			{DayOfWeek: quad.IRI("schema:Monday"), Slot: 2, Opens: "13:00", Closes: "18:00"},
+		},
+	}
+
+	id, err := acme.Insert(c)
+	checkErr(err)
+
+	printTenant(ctx, acme)
+	printTenant(ctx, globex)
+
+	checkErr(acme.MoveClinic(ctx, id, globex, globexAdmin))
+
+	fmt.Println("After moving", c.Name, "to", globex.Label())
+	fmt.Println()
+
+	printTenant(ctx, acme)
+	printTenant(ctx, globex)
+}
+
+func checkErr(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func initializeAndOpenGraph(dbFile string) *cayley.Handle {
+	graph.InitQuadStore("bolt", dbFile, nil)
+
+	// Open and use the database
+	store, err := cayley.NewGraph("bolt", dbFile, nil)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return store
+}
+
+// quadSource is implemented by both *cayley.Handle and *Tenant
+type quadSource interface {
+	QuadsAllIterator() graph.Iterator
+	Quad(graph.Value) quad.Quad
+}
+
+func printTenant(ctx context.Context, t *Tenant) {
+	fmt.Println("=====", t.Label(), "=====")
+	fmt.Println()
+
+	printAdmins(ctx, t)
+	printClinics(ctx, t)
+	printQuads(ctx, t)
+}
+
+func printQuads(ctx context.Context, store quadSource) {
+	// get all quads
+	it := store.QuadsAllIterator()
+	defer it.Close()
+
+	fmt.Println("Quads:")
+	fmt.Println("-----")
+
+	for it.Next(ctx) {
+		fmt.Println(store.Quad(it.Result()))
+	}
+
+	fmt.Println()
+}
+
+func printAdmins(ctx context.Context, t *Tenant) {
+	admins, err := t.Admins(ctx)
+	checkErr(err)
+
+	fmt.Println("Admins:")
+	fmt.Println("------")
+
+	for _, a := range admins {
+		fmt.Println("Name:", a.Name)
+		fmt.Println("Email:", a.Email)
+		fmt.Println("Hashed Password:", a.HashedPassword)
+	}
+
+	fmt.Println()
+}
+
+func printClinics(ctx context.Context, t *Tenant) {
+	clinics, err := t.Clinics(ctx)
+	checkErr(err)
+
+	fmt.Println("Clinics:")
+	fmt.Println("-------")
+
+	for _, c := range clinics {
+		fmt.Println("Name:", c.Name)
+		fmt.Println("Address:", c.Address1)
+		fmt.Println("OfficeTel:", c.OfficeTel)
+		fmt.Println("CreatedBy:", c.CreatedBy)
+
+		for _, h := range c.Hours {
+			fmt.Println("Day", string(h.DayOfWeek))
+			fmt.Println("Slot", h.Slot)
+			fmt.Println("Opens", h.Opens)
+			fmt.Println("Closes", h.Closes)
+		}
+		fmt.Println("----------------------------")
+	}
+
+	fmt.Println()
+}
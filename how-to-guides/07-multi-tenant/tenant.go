@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/graph/path"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	"github.com/cayleygraph/cayley/voc/rdf"
+)
+
+// ownedPredicates point from a clinic to nodes that belong to it and have
+// to travel with it when the clinic moves to another tenant.
+var ownedPredicates = []quad.IRI{
+	quad.IRI("schema:openingHoursSpecification"),
+}
+
+// Tenant is a handle to the part of the store that belongs to one
+// organization. Every quad written through it carries the tenant label and
+// every read only sees quads with that label.
+type Tenant struct {
+	store *cayley.Handle
+	label quad.IRI
+}
+
+// NewTenant returns a handle scoped to the tenant with the given name.
+func NewTenant(store *cayley.Handle, name string) *Tenant {
+	return &Tenant{
+		store: store,
+		label: quad.IRI("tenant:" + name),
+	}
+}
+
+// Label returns the quad label used for this tenant.
+func (t *Tenant) Label() quad.IRI {
+	return t.label
+}
+
+// labelWriter puts a label on every quad before passing it on.
+type labelWriter struct {
+	w     quad.Writer
+	label quad.Value
+}
+
+func (lw labelWriter) WriteQuad(q quad.Quad) error {
+	q.Label = lw.label
+	return lw.w.WriteQuad(q)
+}
+
+func (lw labelWriter) WriteQuads(buf []quad.Quad) (int, error) {
+	// a copy, the caller's quads are left as they are
+	labeled := make([]quad.Quad, len(buf))
+	for i, q := range buf {
+		q.Label = lw.label
+		labeled[i] = q
+	}
+	return lw.w.WriteQuads(labeled)
+}
+
+// Insert writes an object into the tenant.
+func (t *Tenant) Insert(o interface{}) (quad.Value, error) {
+	qw := graph.NewWriter(t.store)
+	id, err := schema.WriteAsQuads(labelWriter{w: qw, label: t.label}, o)
+	// don't forget to close a writer; it has some internal buffering, and
+	// writing what is left of it can fail too
+	if cerr := qw.Close(); err == nil {
+		err = cerr
+	}
+	return id, err
+}
+
+// Path starts a path that only follows quads of this tenant.
+func (t *Tenant) Path(nodes ...quad.Value) *path.Path {
+	return cayley.StartPath(t.store, nodes...).LabelContext(t.label)
+}
+
+// QuadsAllIterator returns an iterator over the quads of this tenant only.
+// It can be used anywhere the store's own QuadsAllIterator was used.
+func (t *Tenant) QuadsAllIterator() graph.Iterator {
+	label := t.store.ValueOf(t.label)
+	if label == nil {
+		// nothing was written for this tenant yet
+		return iterator.NewNull()
+	}
+	return t.store.QuadIterator(quad.Label, label)
+}
+
+// Quad returns the quad for an iterator result.
+func (t *Tenant) Quad(v graph.Value) quad.Quad {
+	return t.store.Quad(v)
+}
+
+// Owns reports whether the node with the given id has a type in this tenant.
+func (t *Tenant) Owns(ctx context.Context, id quad.Value) (bool, error) {
+	v, err := t.Path(id).Out(quad.IRI(rdf.Type)).Iterate(ctx).FirstValue(nil)
+	if err != nil {
+		return false, err
+	}
+	return v != nil, nil
+}
+
+// FindAdminID finds the admin with a given email inside the tenant.
+func (t *Tenant) FindAdminID(ctx context.Context, email string) (quad.IRI, error) {
+	// In respects the label context, so the admin of another tenant with
+	// the same email will not match.
+	p := t.Path(quad.String(email)).In(quad.IRI("email"))
+	id, err := p.Iterate(ctx).FirstValue(nil)
+	if err != nil {
+		return "", err
+	}
+	if id == nil {
+		return "", fmt.Errorf("admin %q not found in %v", email, t.label)
+	}
+
+	return id.(quad.IRI), nil
+}
+
+// ids returns all nodes of the given type inside the tenant.
+func (t *Tenant) ids(ctx context.Context, typ quad.IRI) ([]quad.Value, error) {
+	p := t.Path(typ).In(quad.IRI(rdf.Type))
+	return p.Iterate(ctx).AllValues(nil)
+}
+
+// Admins loads all admins of the tenant.
+func (t *Tenant) Admins(ctx context.Context) ([]Admin, error) {
+	ids, err := t.ids(ctx, quad.IRI("Admin"))
+	if err != nil || len(ids) == 0 {
+		// LoadTo without ids would load the admins of every tenant
+		return nil, err
+	}
+	var admins []Admin
+	err = schema.LoadTo(ctx, t.store, &admins, ids...)
+	return admins, err
+}
+
+// Clinics loads all clinics of the tenant.
+func (t *Tenant) Clinics(ctx context.Context) ([]Clinic, error) {
+	ids, err := t.ids(ctx, quad.IRI("Clinic"))
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	var clinics []Clinic
+	err = schema.LoadTo(ctx, t.store, &clinics, ids...)
+	return clinics, err
+}
+
+// subjectQuads collects the quads of this tenant with id as a subject, and
+// the quads of the nodes id owns.
+func (t *Tenant) subjectQuads(ctx context.Context, id quad.Value) ([]quad.Quad, error) {
+	ref := t.store.ValueOf(id)
+	if ref == nil {
+		return nil, nil
+	}
+
+	it := t.store.QuadIterator(quad.Subject, ref)
+	defer it.Close()
+
+	var quads, owned []quad.Quad
+	for it.Next(ctx) {
+		q := t.store.Quad(it.Result())
+		if q.Label != t.label {
+			continue
+		}
+		quads = append(quads, q)
+		for _, p := range ownedPredicates {
+			if q.Predicate == p {
+				owned = append(owned, q)
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, q := range owned {
+		sub, err := t.subjectQuads(ctx, q.Object)
+		if err != nil {
+			return nil, err
+		}
+		quads = append(quads, sub...)
+	}
+
+	return quads, nil
+}
+
+// MoveClinic moves a clinic together with its opening hours to another
+// tenant. The admins of the old tenant are not visible there, so the clinic
+// is reassigned to an admin of the new tenant.
+func (t *Tenant) MoveClinic(ctx context.Context, id quad.Value, to *Tenant, createdBy quad.IRI) error {
+	if to.label == t.label {
+		return errors.New("clinic is already in " + string(t.label))
+	}
+
+	ok, err := to.Owns(ctx, createdBy)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("admin %v does not belong to %v", createdBy, to.label)
+	}
+
+	quads, err := t.subjectQuads(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(quads) == 0 {
+		return fmt.Errorf("clinic %v not found in %v", id, t.label)
+	}
+
+	tx := cayley.NewTransaction()
+	for _, q := range quads {
+		tx.RemoveQuad(q)
+
+		if q.Subject == id && q.Predicate == quad.IRI("createdBy") {
+			q.Object = createdBy
+		}
+		q.Label = to.label
+		tx.AddQuad(q)
+	}
+
+	return t.store.ApplyTransaction(tx)
+}
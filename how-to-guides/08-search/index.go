@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+)
+
+// How much a match in each field is worth.
+const (
+	nameWeight    = 2.0
+	addressWeight = 1.0
+)
+
+// How much each kind of match is worth.
+const (
+	exactMatch  = 1.0
+	prefixMatch = 0.7
+	fuzzyMatch  = 0.4
+)
+
+// Hit is a clinic found by Search.
+type Hit struct {
+	Clinic Clinic
+	Score  float64
+}
+
+// TextIndex is an in-memory full-text index over the names and addresses
+// of clinics. It is not stored in the graph; Rebuild recreates it from the
+// quad store.
+type TextIndex struct {
+	store *cayley.Handle
+
+	mu       sync.Mutex
+	postings map[string]map[quad.IRI]float64 // token -> clinic -> weight
+	docs     map[quad.IRI][]string           // clinic -> its tokens
+	terms    []string                        // sorted tokens, for prefix search
+}
+
+// NewTextIndex returns an empty index over the clinics of a store.
+func NewTextIndex(store *cayley.Handle) *TextIndex {
+	return &TextIndex{
+		store:    store,
+		postings: make(map[string]map[quad.IRI]float64),
+		docs:     make(map[quad.IRI][]string),
+	}
+}
+
+// Tokenize splits a text into lower case words, ex:
+// "11 Boar St, Singapore" -> 11, boar, st, singapore.
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = strings.ToLower(w)
+	}
+	return words
+}
+
+// Add indexes a clinic, replacing what was indexed for it before.
+func (ix *TextIndex) Add(c Clinic) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(c.ID)
+
+	weights := make(map[string]float64)
+	for _, t := range Tokenize(c.Name) {
+		weights[t] += nameWeight
+	}
+	for _, t := range Tokenize(c.Address1) {
+		weights[t] += addressWeight
+	}
+
+	for t, w := range weights {
+		docs, ok := ix.postings[t]
+		if !ok {
+			docs = make(map[quad.IRI]float64)
+			ix.postings[t] = docs
+			ix.terms = nil
+		}
+		docs[c.ID] = w
+		ix.docs[c.ID] = append(ix.docs[c.ID], t)
+	}
+}
+
+// Remove drops a clinic from the index.
+func (ix *TextIndex) Remove(id quad.IRI) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+func (ix *TextIndex) remove(id quad.IRI) {
+	for _, t := range ix.docs[id] {
+		delete(ix.postings[t], id)
+		if len(ix.postings[t]) == 0 {
+			delete(ix.postings, t)
+			ix.terms = nil
+		}
+	}
+	delete(ix.docs, id)
+}
+
+// sortedTerms returns the indexed tokens in order. It has to be called
+// with the lock held, because it caches the result.
+func (ix *TextIndex) sortedTerms() []string {
+	if ix.terms == nil {
+		ix.terms = make([]string, 0, len(ix.postings))
+		for t := range ix.postings {
+			ix.terms = append(ix.terms, t)
+		}
+		sort.Strings(ix.terms)
+	}
+	return ix.terms
+}
+
+// maxEdits is how many typos we forgive in a word of the query.
+func maxEdits(word string) int {
+	switch n := len([]rune(word)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	}
+	return 2
+}
+
+// matches returns every indexed token that matches a word of the query,
+// with how good the match is.
+func (ix *TextIndex) matches(word string) map[string]float64 {
+	found := make(map[string]float64)
+
+	terms := ix.sortedTerms()
+	for i := sort.SearchStrings(terms, word); i < len(terms) && strings.HasPrefix(terms[i], word); i++ {
+		if terms[i] == word {
+			found[terms[i]] = exactMatch
+		} else {
+			found[terms[i]] = prefixMatch
+		}
+	}
+
+	if edits := maxEdits(word); edits > 0 {
+		for _, t := range terms {
+			if _, ok := found[t]; !ok && levenshtein(word, t) <= edits {
+				found[t] = fuzzyMatch
+			}
+		}
+	}
+
+	return found
+}
+
+// search returns the ids of the clinics matching every word of the query,
+// best first.
+func (ix *TextIndex) search(ctx context.Context, query string) ([]quad.IRI, map[quad.IRI]float64, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	words := Tokenize(query)
+	if len(words) == 0 {
+		return nil, nil, nil
+	}
+
+	var scores map[quad.IRI]float64
+	for _, word := range words {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		// the best match of this word in every clinic
+		best := make(map[quad.IRI]float64)
+		for t, quality := range ix.matches(word) {
+			for id, w := range ix.postings[t] {
+				if s := quality * w; s > best[id] {
+					best[id] = s
+				}
+			}
+		}
+
+		if scores == nil {
+			scores = best
+			continue
+		}
+		for id := range scores {
+			if s, ok := best[id]; ok {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	ids := make([]quad.IRI, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids, scores, nil
+}
+
+// Search returns at most limit clinics matching the query, best first.
+// Every word of the query has to match the name or the address of a clinic,
+// either exactly, as a prefix ("heal" finds "Heal Now" and "Healthy Life")
+// or with a typo ("helthy" finds "Healthy Life").
+func (ix *TextIndex) Search(ctx context.Context, query string, limit int) ([]Hit, error) {
+	ids, scores, err := ix.search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	hits := make([]Hit, 0, len(ids))
+	for _, id := range ids {
+		var c Clinic
+		if err := schema.LoadTo(ctx, ix.store, &c, id); err != nil {
+			return nil, err
+		}
+		hits = append(hits, Hit{Clinic: c, Score: scores[id]})
+	}
+	return hits, nil
+}
+
+// Rebuild throws the index away and indexes every clinic of the store.
+func (ix *TextIndex) Rebuild(ctx context.Context) error {
+	var clinics []Clinic
+	if err := schema.LoadTo(ctx, ix.store, &clinics); err != nil {
+		return err
+	}
+
+	// build a new index on the side, so searches keep working meanwhile
+	fresh := NewTextIndex(ix.store)
+	for _, c := range clinics {
+		fresh.Add(c)
+	}
+
+	ix.mu.Lock()
+	ix.postings, ix.docs, ix.terms = fresh.postings, fresh.docs, nil
+	ix.mu.Unlock()
+	return nil
+}
+
+// levenshtein returns the number of edits needed to turn a into b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
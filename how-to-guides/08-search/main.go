@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"context"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	_ "github.com/cayleygraph/cayley/graph/kv/bolt"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	uuid "github.com/satori/go.uuid"
+)
+
+var dbPath = "db.boltdb"
+
+type Admin struct {
+	Name           string `json:"name" quad:"name"`
+	Email          string `json:"email" quad:"email"`
+	HashedPassword string `json:"hashedPassword"  quad:"hashed_password"`
+}
+
+type Clinic struct {
+	ID        quad.IRI       `json:"id" quad:"@id"`
+	Name      string         `json:"name" quad:"name"`
+	Address1  string         `json:"address" quad:"address"`
+	CreatedBy quad.IRI       `quad:"createdBy"`
+	OfficeTel string         `json:"officeTel" quad:"officeTel"`
+	Hours     []OpeningHours `quad:"schema:openingHoursSpecification"`
+}
+
+type OpeningHours struct {
+	DayOfWeek quad.IRI `json:"day" quad:"schema:dayOfWeek"`
+	Slot      int      `json:"slot" quad:"slot"`
+	Opens     string   `json:"opens" quad:"schema:opens"` // ex: 12:00 or 12:00:00
+	Closes    string   `json:"closes" quad:"schema:closes"`
+}
+
+func init() {
+	schema.RegisterType("Admin", Admin{})
+	schema.RegisterType("Clinic", Clinic{})
+	schema.RegisterType("schema:OpeningHoursSpecification", OpeningHours{})
+	schema.GenerateID = func(_ interface{}) quad.Value {
+		return quad.IRI(uuid.NewV1().String())
+	}
+}
+
+func main() {
+	os.RemoveAll(dbPath)
+	store := initializeAndOpenGraph(dbPath)
+	index := NewTextIndex(store)
+	ctx := context.TODO()
+
+	a := Admin{
+		Name:           "Josh",
+		Email:          "josh_f@gmail.com",
+		HashedPassword: "435iue8uou9eu",
+	}
+	_, err := insert(store, a)
+	checkErr(err)
+
+	adminId, err := findAdminID(store, a.Email)
+	checkErr(err)
+
+	clinics := []Clinic{
+		{Name: "Heal Now", Address1: "3234 Rot Road, Singapore", OfficeTel: "65 6100 0939"},
+		{Name: "Healthy Life", Address1: "11 boar st, Singapore 11233", OfficeTel: "65 6100 0940"},
+		{Name: "Raffles Medical", Address1: "585 North Bridge Road, Singapore 188770", OfficeTel: "65 6311 1111"},
+	}
+	for _, c := range clinics {
+		c.CreatedBy = adminId
+		_, err := insertClinic(store, index, c)
+		checkErr(err)
+	}
+
+	search(ctx, index, "heal")
+	search(ctx, index, "helthy")
+	search(ctx, index, "road singapore")
+
+	// the index follows updates
+	id, err := findClinicID(ctx, store, "Heal Now")
+	checkErr(err)
+	checkErr(updateAddress(ctx, store, index, id, "3234 Rot Road, Singapore", "1 Orchard Rd, Singapore"))
+	search(ctx, index, "orchard")
+
+	// and can always be recreated from the store
+	checkErr(index.Rebuild(ctx))
+	search(ctx, index, "raffles")
+}
+
+func checkErr(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func initializeAndOpenGraph(dbFile string) *cayley.Handle {
+	graph.InitQuadStore("bolt", dbFile, nil)
+
+	// Open and use the database
+	store, err := cayley.NewGraph("bolt", dbFile, nil)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return store
+}
+
+func insert(h *cayley.Handle, o interface{}) (quad.Value, error) {
+	qw := graph.NewWriter(h)
+	id, err := schema.WriteAsQuads(qw, o)
+	// don't forget to close a writer; it has some internal buffering, and
+	// writing what is left of it can fail too
+	if cerr := qw.Close(); err == nil {
+		err = cerr
+	}
+	return id, err
+}
+
+// insertClinic writes a clinic and adds it to the text index.
+func insertClinic(h *cayley.Handle, index *TextIndex, c Clinic) (quad.Value, error) {
+	// an ID field is written as it is, so every clinic without one would be <>
+	c.ID = quad.IRI(uuid.NewV1().String())
+	if _, err := insert(h, c); err != nil {
+		return nil, err
+	}
+	index.Add(c)
+	return c.ID, nil
+}
+
+// updateAddress changes the address of a clinic and indexes it again.
+func updateAddress(ctx context.Context, h *cayley.Handle, index *TextIndex, id quad.Value, from, to string) error {
+	t := cayley.NewTransaction()
+	t.RemoveQuad(quad.Make(id, quad.IRI("address"), from, nil))
+	t.AddQuad(quad.Make(id, quad.IRI("address"), to, nil))
+
+	if err := h.ApplyTransaction(t); err != nil {
+		return err
+	}
+
+	var c Clinic
+	if err := schema.LoadTo(ctx, h, &c, id); err != nil {
+		return err
+	}
+	index.Add(c)
+	return nil
+}
+
+func findAdminID(store *cayley.Handle, email string) (quad.IRI, error) {
+	p := cayley.StartPath(store).Has(quad.IRI("email"), quad.String(email))
+	id, err := p.Iterate(nil).FirstValue(nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	return id.(quad.IRI), nil
+}
+
+func findClinicID(ctx context.Context, store *cayley.Handle, name string) (quad.IRI, error) {
+	p := cayley.StartPath(store).Has(quad.IRI("name"), quad.String(name))
+	id, err := p.Iterate(ctx).FirstValue(nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	return id.(quad.IRI), nil
+}
+
+func search(ctx context.Context, index *TextIndex, query string) {
+	hits, err := index.Search(ctx, query, 10)
+	checkErr(err)
+
+	fmt.Printf("Search %q:\n", query)
+	fmt.Println("-------")
+
+	for _, h := range hits {
+		fmt.Printf("%.2f %s, %s\n", h.Score, h.Clinic.Name, h.Clinic.Address1)
+	}
+
+	fmt.Println()
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+)
+
+const (
+	earthRadiusKm = 6371.0
+	kmPerDegree   = 2 * math.Pi * earthRadiusKm / 360
+
+	// cellSize is the size of a grid cell in degrees, about 5.5km at the
+	// equator.
+	cellSize = 0.05
+)
+
+// Distance returns the great-circle distance between two points in km.
+func Distance(a, b GeoCoordinates) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Filter selects clinics in geo queries, ex: OpenAt.
+type Filter func(c Clinic) bool
+
+// GeoHit is a clinic found by a geo query.
+type GeoHit struct {
+	Clinic     Clinic
+	DistanceKm float64
+}
+
+type cell struct {
+	lat, lon int
+}
+
+func cellOf(p GeoCoordinates) cell {
+	return cell{
+		lat: int(math.Floor(p.Latitude / cellSize)),
+		lon: int(math.Floor(p.Longitude / cellSize)),
+	}
+}
+
+// GeoIndex is an in-memory grid over the locations of clinics. It is not
+// stored in the graph; Rebuild recreates it from the quad store.
+type GeoIndex struct {
+	store *cayley.Handle
+
+	mu     sync.RWMutex
+	cells  map[cell][]quad.IRI
+	points map[quad.IRI]GeoCoordinates
+}
+
+// NewGeoIndex returns an empty index over the clinics of a store.
+func NewGeoIndex(store *cayley.Handle) *GeoIndex {
+	return &GeoIndex{
+		store:  store,
+		cells:  make(map[cell][]quad.IRI),
+		points: make(map[quad.IRI]GeoCoordinates),
+	}
+}
+
+// Add indexes the location of a clinic, replacing the old one. Clinics
+// without a location are removed from the index.
+func (ix *GeoIndex) Add(c Clinic) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(c.ID)
+	if c.Geo == nil {
+		return
+	}
+
+	k := cellOf(*c.Geo)
+	ix.cells[k] = append(ix.cells[k], c.ID)
+	ix.points[c.ID] = *c.Geo
+}
+
+// Remove drops a clinic from the index.
+func (ix *GeoIndex) Remove(id quad.IRI) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+func (ix *GeoIndex) remove(id quad.IRI) {
+	p, ok := ix.points[id]
+	if !ok {
+		return
+	}
+	k := cellOf(p)
+	ids := ix.cells[k]
+	for i := range ids {
+		if ids[i] == id {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(ix.cells, k)
+	} else {
+		ix.cells[k] = ids
+	}
+	delete(ix.points, id)
+}
+
+type candidate struct {
+	id   quad.IRI
+	dist float64
+}
+
+// ring returns the clinics in the cells at exactly r cells from the center
+// cell, with their distance to the center.
+func (ix *GeoIndex) ring(center GeoCoordinates, c cell, r int) []candidate {
+	var found []candidate
+	for lat := c.lat - r; lat <= c.lat+r; lat++ {
+		for lon := c.lon - r; lon <= c.lon+r; lon++ {
+			if lat != c.lat-r && lat != c.lat+r && lon != c.lon-r && lon != c.lon+r {
+				continue // inside the ring, already seen
+			}
+			for _, id := range ix.cells[cell{lat: lat, lon: lon}] {
+				found = append(found, candidate{id: id, dist: Distance(center, ix.points[id])})
+			}
+		}
+	}
+	return found
+}
+
+// ringDistance is a lower bound of the distance from the center to any
+// point in ring r or further out. The center can be anywhere in its cell,
+// so a point of ring r is at least r-1 full cells away.
+func ringDistance(center GeoCoordinates, r int) float64 {
+	if r <= 1 {
+		return 0
+	}
+	// a degree of longitude is shortest at the highest latitude of the rings
+	lat := math.Min(90, math.Abs(center.Latitude)+float64(r+1)*cellSize)
+	perLon := kmPerDegree * math.Cos(lat*math.Pi/180)
+	return float64(r-1) * cellSize * math.Min(kmPerDegree, perLon)
+}
+
+// maxRing is the number of rings after which every cell has been seen.
+func (ix *GeoIndex) maxRing(c cell) int {
+	max := 0
+	for k := range ix.cells {
+		if d := abs(k.lat - c.lat); d > max {
+			max = d
+		}
+		if d := abs(k.lon - c.lon); d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+// candidates walks the rings around center, nearest first, and calls fn
+// with the clinics of every ring until fn returns false.
+func (ix *GeoIndex) candidates(center GeoCoordinates, fn func(r int, found []candidate) bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	c := cellOf(center)
+	last := ix.maxRing(c)
+	for r := 0; r <= last; r++ {
+		found := ix.ring(center, c, r)
+		sort.Slice(found, func(i, j int) bool { return found[i].dist < found[j].dist })
+		if !fn(r, found) {
+			return
+		}
+	}
+}
+
+func (ix *GeoIndex) load(ctx context.Context, id quad.IRI) (Clinic, error) {
+	var c Clinic
+	err := schema.LoadTo(ctx, ix.store, &c, id)
+	return c, err
+}
+
+func matches(c Clinic, filters []Filter) bool {
+	for _, f := range filters {
+		if !f(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Within returns the clinics at most km away from center that pass all
+// filters, nearest first.
+func (ix *GeoIndex) Within(ctx context.Context, center GeoCoordinates, km float64, filters ...Filter) ([]GeoHit, error) {
+	var (
+		near []candidate
+		err  error
+	)
+	ix.candidates(center, func(r int, found []candidate) bool {
+		if err = ctx.Err(); err != nil {
+			return false
+		}
+		for _, c := range found {
+			if c.dist <= km {
+				near = append(near, c)
+			}
+		}
+		return ringDistance(center, r+1) <= km
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(near, func(i, j int) bool { return near[i].dist < near[j].dist })
+
+	var hits []GeoHit
+	for _, n := range near {
+		c, err := ix.load(ctx, n.id)
+		if err != nil {
+			return nil, err
+		}
+		if matches(c, filters) {
+			hits = append(hits, GeoHit{Clinic: c, DistanceKm: n.dist})
+		}
+	}
+	return hits, nil
+}
+
+// Nearest returns the k clinics nearest to center that pass all filters,
+// nearest first.
+func (ix *GeoIndex) Nearest(ctx context.Context, center GeoCoordinates, k int, filters ...Filter) ([]GeoHit, error) {
+	var (
+		pending []candidate // seen, but maybe something nearer is in the next ring
+		hits    []GeoHit
+		err     error
+	)
+	ix.candidates(center, func(r int, found []candidate) bool {
+		if err = ctx.Err(); err != nil {
+			return false
+		}
+		pending = append(pending, found...)
+		sort.Slice(pending, func(i, j int) bool { return pending[i].dist < pending[j].dist })
+
+		// everything closer than the next ring is final
+		bound := ringDistance(center, r+1)
+		for len(pending) > 0 && pending[0].dist <= bound && len(hits) < k {
+			var c Clinic
+			c, err = ix.load(ctx, pending[0].id)
+			if err != nil {
+				return false
+			}
+			if matches(c, filters) {
+				hits = append(hits, GeoHit{Clinic: c, DistanceKm: pending[0].dist})
+			}
+			pending = pending[1:]
+		}
+		return len(hits) < k
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the last ring was seen, what is pending is in order
+	for len(pending) > 0 && len(hits) < k {
+		c, err := ix.load(ctx, pending[0].id)
+		if err != nil {
+			return nil, err
+		}
+		if matches(c, filters) {
+			hits = append(hits, GeoHit{Clinic: c, DistanceKm: pending[0].dist})
+		}
+		pending = pending[1:]
+	}
+	return hits, nil
+}
+
+// Rebuild throws the index away and indexes every clinic of the store.
+func (ix *GeoIndex) Rebuild(ctx context.Context) error {
+	var clinics []Clinic
+	if err := schema.LoadTo(ctx, ix.store, &clinics); err != nil {
+		return err
+	}
+
+	fresh := NewGeoIndex(ix.store)
+	for _, c := range clinics {
+		fresh.Add(c)
+	}
+
+	ix.mu.Lock()
+	ix.cells, ix.points = fresh.cells, fresh.points
+	ix.mu.Unlock()
+	return nil
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"context"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	_ "github.com/cayleygraph/cayley/graph/kv/bolt"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	"github.com/cayleygraph/cayley/voc/rdf"
+	uuid "github.com/satori/go.uuid"
+)
+
+var dbPath = "db.boltdb"
+
+type Admin struct {
+	Name           string `json:"name" quad:"name"`
+	Email          string `json:"email" quad:"email"`
+	HashedPassword string `json:"hashedPassword"  quad:"hashed_password"`
+}
+
+type Clinic struct {
+	ID        quad.IRI        `json:"id" quad:"@id"`
+	Name      string          `json:"name" quad:"name"`
+	Address1  string          `json:"address" quad:"address"`
+	CreatedBy quad.IRI        `quad:"createdBy"`
+	OfficeTel string          `json:"officeTel" quad:"officeTel"`
+	Geo       *GeoCoordinates `json:"geo" quad:"schema:geo,optional"` // clinics added before this guide have no location
+	Hours     []OpeningHours  `quad:"schema:openingHoursSpecification"`
+}
+
+type GeoCoordinates struct {
+	ID        quad.IRI `json:"-" quad:"@id"`
+	Latitude  float64  `json:"latitude" quad:"schema:latitude"`
+	Longitude float64  `json:"longitude" quad:"schema:longitude"`
+}
+
+type OpeningHours struct {
+	DayOfWeek quad.IRI `json:"day" quad:"schema:dayOfWeek"`
+	Slot      int      `json:"slot" quad:"slot"`
+	Opens     string   `json:"opens" quad:"schema:opens"` // ex: 12:00 or 12:00:00
+	Closes    string   `json:"closes" quad:"schema:closes"`
+}
+
+// days maps Go weekdays to the schema.org days used in DayOfWeek.
+var days = map[time.Weekday]quad.IRI{
+	time.Monday:    "schema:Monday",
+	time.Tuesday:   "schema:Tuesday",
+	time.Wednesday: "schema:Wednesday",
+	time.Thursday:  "schema:Thursday",
+	time.Friday:    "schema:Friday",
+	time.Saturday:  "schema:Saturday",
+	time.Sunday:    "schema:Sunday",
+}
+
+func init() {
+	schema.RegisterType("Admin", Admin{})
+	schema.RegisterType("Clinic", Clinic{})
+	schema.RegisterType("schema:GeoCoordinates", GeoCoordinates{})
+	schema.RegisterType("schema:OpeningHoursSpecification", OpeningHours{})
+	schema.GenerateID = func(_ interface{}) quad.Value {
+		return quad.IRI(uuid.NewV1().String())
+	}
+}
+
+// OpenAt keeps the clinics that are open at the given time.
+func OpenAt(t time.Time) Filter {
+	day := days[t.Weekday()]
+	hhmm := t.Format("15:04")
+	return func(c Clinic) bool {
+		for _, h := range c.Hours {
+			// "08:00" <= "09:30" < "12:00" works on strings as long as
+			// hours have two digits
+			if h.DayOfWeek == day && h.Opens <= hhmm && hhmm < h.Closes {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func main() {
+	os.RemoveAll(dbPath)
+	store := initializeAndOpenGraph(dbPath)
+	index := NewGeoIndex(store)
+	ctx := context.TODO()
+
+	a := Admin{
+		Name:           "Josh",
+		Email:          "josh_f@gmail.com",
+		HashedPassword: "435iue8uou9eu",
+	}
+	_, err := insert(store, a)
+	checkErr(err)
+
+	adminId, err := findAdminID(store, a.Email)
+	checkErr(err)
+
+	weekdays := func(opens, closes string) []OpeningHours {
+		var hours []OpeningHours
+		for _, d := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday} {
+			hours = append(hours, OpeningHours{DayOfWeek: days[d], Slot: 1, Opens: opens, Closes: closes})
+		}
+		return hours
+	}
+
+	clinics := []Clinic{
+		{
+			Name:      "Heal Now",
+			Address1:  "3234 Rot Road, Singapore",
+			OfficeTel: "65 6100 0939",
+			Geo:       &GeoCoordinates{Latitude: 1.3048, Longitude: 103.8318},
+			Hours:     weekdays("08:00", "12:00"),
+		},
+		{
+			Name:      "Healthy Life",
+			Address1:  "11 boar st, Singapore 11233",
+			OfficeTel: "65 6100 0940",
+			Geo:       &GeoCoordinates{Latitude: 1.2839, Longitude: 103.8515},
+			Hours:     weekdays("13:00", "18:00"),
+		},
+		{
+			Name:      "Raffles Medical",
+			Address1:  "585 North Bridge Road, Singapore 188770",
+			OfficeTel: "65 6311 1111",
+			Geo:       &GeoCoordinates{Latitude: 1.3008, Longitude: 103.8584},
+			Hours:     weekdays("08:00", "18:00"),
+		},
+		{
+			Name:      "Johor Family Clinic",
+			Address1:  "12 Jalan Wong Ah Fook, Johor Bahru",
+			OfficeTel: "60 7 224 1010",
+			Geo:       &GeoCoordinates{Latitude: 1.4655, Longitude: 103.7578},
+			Hours:     weekdays("09:00", "17:00"),
+		},
+	}
+	for _, c := range clinics {
+		c.CreatedBy = adminId
+		_, err := insertClinic(store, index, c)
+		checkErr(err)
+	}
+
+	orchard := GeoCoordinates{Latitude: 1.3040, Longitude: 103.8318}
+
+	hits, err := index.Within(ctx, orchard, 5)
+	checkErr(err)
+	printHits("Within 5km of Orchard Road", hits)
+
+	hits, err = index.Nearest(ctx, orchard, 2)
+	checkErr(err)
+	printHits("2 nearest to Orchard Road", hits)
+
+	// Monday 2pm
+	monday := time.Date(2017, 5, 29, 14, 0, 0, 0, time.UTC)
+	hits, err = index.Nearest(ctx, orchard, 2, OpenAt(monday))
+	checkErr(err)
+	printHits("2 nearest to Orchard Road, open on Monday at 14:00", hits)
+
+	// the index follows updates
+	id, err := findClinicID(ctx, store, "Johor Family Clinic")
+	checkErr(err)
+	checkErr(updateGeo(ctx, store, index, id, GeoCoordinates{Latitude: 1.3050, Longitude: 103.8320}))
+
+	hits, err = index.Within(ctx, orchard, 1)
+	checkErr(err)
+	printHits("Within 1km of Orchard Road, after the move", hits)
+
+	// and can always be recreated from the store
+	checkErr(index.Rebuild(ctx))
+}
+
+func checkErr(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func initializeAndOpenGraph(dbFile string) *cayley.Handle {
+	graph.InitQuadStore("bolt", dbFile, nil)
+
+	// Open and use the database
+	store, err := cayley.NewGraph("bolt", dbFile, nil)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return store
+}
+
+func insert(h *cayley.Handle, o interface{}) (quad.Value, error) {
+	qw := graph.NewWriter(h)
+	id, err := schema.WriteAsQuads(qw, o)
+	// don't forget to close a writer; it has some internal buffering, and
+	// writing what is left of it can fail too
+	if cerr := qw.Close(); err == nil {
+		err = cerr
+	}
+	return id, err
+}
+
+// insertClinic writes a clinic and adds it to the geo index.
+func insertClinic(h *cayley.Handle, index *GeoIndex, c Clinic) (quad.Value, error) {
+	// ID fields are written as they are, so every clinic and location
+	// without one would be <>
+	c.ID = quad.IRI(uuid.NewV1().String())
+	if c.Geo != nil {
+		geo := *c.Geo
+		geo.ID = quad.IRI(uuid.NewV1().String())
+		c.Geo = &geo
+	}
+	if _, err := insert(h, c); err != nil {
+		return nil, err
+	}
+	index.Add(c)
+	return c.ID, nil
+}
+
+// updateGeo moves a clinic and updates the geo index.
+func updateGeo(ctx context.Context, h *cayley.Handle, index *GeoIndex, id quad.Value, to GeoCoordinates) error {
+	var c Clinic
+	if err := schema.LoadTo(ctx, h, &c, id); err != nil {
+		return err
+	}
+
+	t := cayley.NewTransaction()
+	if c.Geo == nil {
+		to.ID = quad.IRI(uuid.NewV1().String())
+		t.AddQuad(quad.Make(id, quad.IRI("schema:geo"), to.ID, nil))
+		t.AddQuad(quad.Make(to.ID, quad.IRI(rdf.Type), quad.IRI("schema:GeoCoordinates"), nil))
+	} else {
+		to.ID = c.Geo.ID
+		t.RemoveQuad(quad.Make(to.ID, quad.IRI("schema:latitude"), quad.Float(c.Geo.Latitude), nil))
+		t.RemoveQuad(quad.Make(to.ID, quad.IRI("schema:longitude"), quad.Float(c.Geo.Longitude), nil))
+	}
+	t.AddQuad(quad.Make(to.ID, quad.IRI("schema:latitude"), quad.Float(to.Latitude), nil))
+	t.AddQuad(quad.Make(to.ID, quad.IRI("schema:longitude"), quad.Float(to.Longitude), nil))
+
+	if err := h.ApplyTransaction(t); err != nil {
+		return err
+	}
+
+	c.Geo = &to
+	index.Add(c)
+	return nil
+}
+
+func findAdminID(store *cayley.Handle, email string) (quad.IRI, error) {
+	p := cayley.StartPath(store).Has(quad.IRI("email"), quad.String(email))
+	id, err := p.Iterate(nil).FirstValue(nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	return id.(quad.IRI), nil
+}
+
+func findClinicID(ctx context.Context, store *cayley.Handle, name string) (quad.IRI, error) {
+	p := cayley.StartPath(store).Has(quad.IRI("name"), quad.String(name))
+	id, err := p.Iterate(ctx).FirstValue(nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	return id.(quad.IRI), nil
+}
+
+func printHits(title string, hits []GeoHit) {
+	fmt.Println(title + ":")
+	fmt.Println("-------")
+
+	for _, h := range hits {
+		fmt.Printf("%5.2fkm %s, %s\n", h.DistanceKm, h.Clinic.Name, h.Clinic.Address1)
+	}
+
+	fmt.Println()
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	"github.com/cayleygraph/cayley/voc/rdf"
+)
+
+// SortBy is the order of a listing.
+type SortBy string
+
+const (
+	ByName    SortBy = "name"
+	ByCreated SortBy = "createdAt"
+)
+
+// DefaultLimit is the page size used when ListOptions.Limit is 0.
+const DefaultLimit = 20
+
+// ErrBadCursor is returned for cursors that were not returned by a listing
+// with the same order.
+var ErrBadCursor = errors.New("invalid cursor")
+
+// ListOptions select a page of a listing.
+type ListOptions struct {
+	SortBy SortBy
+	Desc   bool
+	Limit  int
+
+	// Cursor is the Next value of the previous page, empty for the first
+	// page.
+	Cursor string
+}
+
+// cursor is the position of the last item of a page. It is sent to clients
+// base64 encoded.
+type cursor struct {
+	SortBy SortBy `json:"s"`
+	Desc   bool   `json:"d,omitempty"`
+	Key    string `json:"k"`
+	ID     string `json:"i"`
+}
+
+func (c cursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func parseCursor(s string, opts ListOptions) (*cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrBadCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.SortBy != opts.SortBy || c.Desc != opts.Desc {
+		return nil, ErrBadCursor
+	}
+	return &c, nil
+}
+
+// item is a node and the value it is sorted by.
+type item struct {
+	key string
+	id  quad.Value
+}
+
+// before tells if a comes before b in the listing. Nodes with the same key
+// are sorted by id, so the order is stable between pages. An item is not
+// before itself, in either order, so the last item of a page is not listed
+// again on the next one.
+func before(a, b item, desc bool) bool {
+	if desc {
+		a, b = b, a
+	}
+	if a.key != b.key {
+		return a.key < b.key
+	}
+	return a.id.String() < b.id.String()
+}
+
+// window keeps the first n items it is given, in a heap with the last of
+// them on top, so memory does not grow with the size of the store.
+type window struct {
+	items []item
+	desc  bool
+}
+
+func (w *window) Len() int           { return len(w.items) }
+func (w *window) Less(i, j int) bool { return before(w.items[j], w.items[i], w.desc) }
+func (w *window) Swap(i, j int)      { w.items[i], w.items[j] = w.items[j], w.items[i] }
+func (w *window) Push(x interface{}) { w.items = append(w.items, x.(item)) }
+func (w *window) Pop() interface{} {
+	last := w.items[len(w.items)-1]
+	w.items = w.items[:len(w.items)-1]
+	return last
+}
+
+// sortKey turns a value into a string that sorts like the value.
+func sortKey(v quad.Value) string {
+	switch v := v.(type) {
+	case quad.String:
+		return strings.ToLower(string(v))
+	case quad.Time:
+		// fixed width, so strings sort like times
+		return time.Time(v).UTC().Format("2006-01-02T15:04:05.000000000Z")
+	case nil:
+		return ""
+	}
+	return v.String()
+}
+
+// listIDs returns one page of the ids of the nodes of a type, and the
+// cursor of the next page. The nodes are streamed from a cayley iterator and
+// only limit+1 of them are kept in memory.
+func listIDs(ctx context.Context, store *cayley.Handle, typ quad.IRI, opts ListOptions) ([]quad.Value, string, error) {
+	if opts.SortBy == "" {
+		opts.SortBy = ByName
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultLimit
+	}
+	after, err := parseCursor(opts.Cursor, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	p := cayley.StartPath(store).
+		Has(quad.IRI(rdf.Type), typ).
+		Tag("id").
+		SaveOptional(quad.IRI(opts.SortBy), "key")
+
+	w := &window{desc: opts.Desc}
+	err = p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		it := item{key: sortKey(m["key"]), id: m["id"]}
+		if after != nil && !before(item{key: after.Key, id: quad.IRI(after.ID)}, it, opts.Desc) {
+			return // on a previous page
+		}
+		heap.Push(w, it)
+		if w.Len() > opts.Limit+1 {
+			heap.Pop(w)
+		}
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	items := w.items
+	sort.Slice(items, func(i, j int) bool { return before(items[i], items[j], opts.Desc) })
+
+	var next string
+	if len(items) > opts.Limit {
+		items = items[:opts.Limit]
+		last := items[len(items)-1]
+		id, ok := last.id.(quad.IRI)
+		if !ok {
+			// cursors keep IRIs only, ex: not the blank nodes of an imported store
+			return nil, "", fmt.Errorf("can't page after %v: not an IRI", last.id)
+		}
+		next = cursor{
+			SortBy: opts.SortBy,
+			Desc:   opts.Desc,
+			Key:    last.key,
+			ID:     string(id),
+		}.String()
+	}
+
+	ids := make([]quad.Value, len(items))
+	for i, it := range items {
+		ids[i] = it.id
+	}
+	return ids, next, nil
+}
+
+// ClinicPage is one page of ListClinics.
+type ClinicPage struct {
+	Clinics []Clinic
+	Next    string // cursor of the next page, empty on the last page
+}
+
+// ListClinics returns a page of clinics.
+func ListClinics(ctx context.Context, store *cayley.Handle, opts ListOptions) (*ClinicPage, error) {
+	ids, next, err := listIDs(ctx, store, quad.IRI("Clinic"), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ClinicPage{Next: next}
+	for _, id := range ids {
+		// one at a time, to keep the order of the page
+		var c Clinic
+		if err := schema.LoadTo(ctx, store, &c, id); err != nil {
+			return nil, err
+		}
+		page.Clinics = append(page.Clinics, c)
+	}
+	return page, nil
+}
+
+// AdminPage is one page of ListAdmins.
+type AdminPage struct {
+	Admins []Admin
+	Next   string // cursor of the next page, empty on the last page
+}
+
+// ListAdmins returns a page of admins.
+func ListAdmins(ctx context.Context, store *cayley.Handle, opts ListOptions) (*AdminPage, error) {
+	ids, next, err := listIDs(ctx, store, quad.IRI("Admin"), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &AdminPage{Next: next}
+	for _, id := range ids {
+		var a Admin
+		if err := schema.LoadTo(ctx, store, &a, id); err != nil {
+			return nil, err
+		}
+		page.Admins = append(page.Admins, a)
+	}
+	return page, nil
+}
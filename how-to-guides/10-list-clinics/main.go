@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"context"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	_ "github.com/cayleygraph/cayley/graph/kv/bolt"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	uuid "github.com/satori/go.uuid"
+)
+
+var dbPath = "db.boltdb"
+
+type Admin struct {
+	Name           string    `json:"name" quad:"name"`
+	Email          string    `json:"email" quad:"email"`
+	HashedPassword string    `json:"hashedPassword"  quad:"hashed_password"`
+	CreatedAt      time.Time `json:"createdAt" quad:"createdAt,optional"`
+}
+
+type Clinic struct {
+	ID        quad.IRI       `json:"id" quad:"@id"`
+	Name      string         `json:"name" quad:"name"`
+	Address1  string         `json:"address" quad:"address"`
+	CreatedBy quad.IRI       `quad:"createdBy"`
+	CreatedAt time.Time      `json:"createdAt" quad:"createdAt,optional"` // clinics added before this guide have no creation time
+	OfficeTel string         `json:"officeTel" quad:"officeTel"`
+	Hours     []OpeningHours `quad:"schema:openingHoursSpecification"`
+}
+
+type OpeningHours struct {
+	DayOfWeek quad.IRI `json:"day" quad:"schema:dayOfWeek"`
+	Slot      int      `json:"slot" quad:"slot"`
+	Opens     string   `json:"opens" quad:"schema:opens"` // ex: 12:00 or 12:00:00
+	Closes    string   `json:"closes" quad:"schema:closes"`
+}
+
+func init() {
+	schema.RegisterType("Admin", Admin{})
+	schema.RegisterType("Clinic", Clinic{})
+	schema.RegisterType("schema:OpeningHoursSpecification", OpeningHours{})
+	schema.GenerateID = func(_ interface{}) quad.Value {
+		return quad.IRI(uuid.NewV1().String())
+	}
+}
+
+func main() {
+	os.RemoveAll(dbPath)
+	store := initializeAndOpenGraph(dbPath)
+	ctx := context.TODO()
+
+	a := Admin{
+		Name:           "Josh",
+		Email:          "josh_f@gmail.com",
+		HashedPassword: "435iue8uou9eu",
+		CreatedAt:      time.Now(),
+	}
+	_, err := insert(store, a)
+	checkErr(err)
+
+	adminId, err := findAdminID(store, a.Email)
+	checkErr(err)
+
+	names := []string{"Raffles Medical", "Heal Now", "Healthy Life", "Orchard Clinic", "Bukit Family Clinic"}
+	for i, name := range names {
+		c := Clinic{
+			// an ID field is written as it is, so every clinic without one would be <>
+			ID:        quad.IRI(uuid.NewV1().String()),
+			Name:      name,
+			Address1:  fmt.Sprintf("%d Orchard Rd, Singapore", i+1),
+			CreatedBy: adminId,
+			CreatedAt: time.Date(2017, 5, 1+i, 9, 0, 0, 0, time.UTC),
+			OfficeTel: fmt.Sprintf("65 6100 %04d", i+1),
+		}
+		_, err := insert(store, c)
+		checkErr(err)
+	}
+
+	printAdmins(ctx, store)
+	printClinics(ctx, store, ListOptions{SortBy: ByName, Limit: 2})
+	printClinics(ctx, store, ListOptions{SortBy: ByCreated, Desc: true, Limit: 2})
+}
+
+func checkErr(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func initializeAndOpenGraph(dbFile string) *cayley.Handle {
+	graph.InitQuadStore("bolt", dbFile, nil)
+
+	// Open and use the database
+	store, err := cayley.NewGraph("bolt", dbFile, nil)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return store
+}
+
+func insert(h *cayley.Handle, o interface{}) (quad.Value, error) {
+	qw := graph.NewWriter(h)
+	id, err := schema.WriteAsQuads(qw, o)
+	// don't forget to close a writer; it has some internal buffering, and
+	// writing what is left of it can fail too
+	if cerr := qw.Close(); err == nil {
+		err = cerr
+	}
+	return id, err
+}
+
+func findAdminID(store *cayley.Handle, email string) (quad.IRI, error) {
+	p := cayley.StartPath(store).Has(quad.IRI("email"), quad.String(email))
+	id, err := p.Iterate(nil).FirstValue(nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	return id.(quad.IRI), nil
+}
+
+func printAdmins(ctx context.Context, store *cayley.Handle) {
+	fmt.Println("Admins:")
+	fmt.Println("------")
+
+	opts := ListOptions{SortBy: ByName}
+	for {
+		page, err := ListAdmins(ctx, store, opts)
+		checkErr(err)
+
+		for _, a := range page.Admins {
+			fmt.Println("Name:", a.Name)
+			fmt.Println("Email:", a.Email)
+		}
+
+		if page.Next == "" {
+			break
+		}
+		opts.Cursor = page.Next
+	}
+
+	fmt.Println()
+}
+
+// printClinics prints every clinic, one page at a time.
+func printClinics(ctx context.Context, store *cayley.Handle, opts ListOptions) {
+	order := string(opts.SortBy)
+	if opts.Desc {
+		order += ", descending"
+	}
+	fmt.Printf("Clinics by %s:\n", order)
+	fmt.Println("-------")
+
+	for n := 1; ; n++ {
+		page, err := ListClinics(ctx, store, opts)
+		checkErr(err)
+
+		fmt.Printf("page %d\n", n)
+		for _, c := range page.Clinics {
+			fmt.Printf("  %s (%s)\n", c.Name, c.CreatedAt.Format("2006-01-02"))
+		}
+
+		if page.Next == "" {
+			break
+		}
+		opts.Cursor = page.Next
+	}
+
+	fmt.Println()
+}
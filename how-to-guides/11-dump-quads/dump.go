@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/quad/nquads"
+	"github.com/cayleygraph/cayley/voc/rdf"
+)
+
+// Formats lists the names accepted by NewQuadWriter.
+var Formats = []string{"nquads", "ndjson", "csv", "table"}
+
+// QuadWriter writes quads one at a time. Close flushes what is buffered; it
+// does not close the underlying io.Writer.
+type QuadWriter interface {
+	WriteQuad(q quad.Quad) error
+	Close() error
+}
+
+// NewQuadWriter returns a writer for one of Formats.
+func NewQuadWriter(w io.Writer, format string) (QuadWriter, error) {
+	switch format {
+	case "nquads", "nq", "":
+		return nquads.NewWriter(w), nil
+	case "ndjson", "jsonl":
+		enc := json.NewEncoder(w)
+		// IRIs are written as "<name>", not "\u003cname\u003e"
+		enc.SetEscapeHTML(false)
+		return &jsonWriter{enc: enc}, nil
+	case "csv":
+		return newCSVWriter(w), nil
+	case "table":
+		return newTableWriter(w), nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// Filter selects the quads of a dump. Empty fields match everything.
+type Filter struct {
+	Subject   quad.Value
+	Predicate quad.IRI
+	Type      quad.IRI // only the quads of nodes of this rdf:type
+}
+
+func (f Filter) matches(q quad.Quad) bool {
+	if f.Subject != nil && q.Subject != f.Subject {
+		return false
+	}
+	if f.Predicate != "" && q.Predicate != f.Predicate {
+		return false
+	}
+	return true
+}
+
+// hasType tells if a node has the type of the filter.
+func (f Filter) hasType(ctx context.Context, store *cayley.Handle, id quad.Value) (bool, error) {
+	if f.Type == "" {
+		return true, nil
+	}
+	v, err := cayley.StartPath(store, id).Has(quad.IRI(rdf.Type), f.Type).Iterate(ctx).FirstValue(nil)
+	return v != nil, err
+}
+
+// Dump writes the quads of the store that pass the filter and returns how
+// many were written. Quads are streamed from the iterators of the store, so
+// the size of the store does not matter.
+func Dump(ctx context.Context, store *cayley.Handle, w QuadWriter, f Filter) (int, error) {
+	n := 0
+	write := func(q quad.Quad) error {
+		if !f.matches(q) {
+			return nil
+		}
+		n++
+		return w.WriteQuad(q)
+	}
+
+	var err error
+	switch {
+	case f.Subject != nil:
+		var ok bool
+		ok, err = f.hasType(ctx, store, f.Subject)
+		if err == nil && ok {
+			err = eachQuad(ctx, store, quad.Subject, f.Subject, write)
+		}
+	case f.Type != "":
+		// every node of the type, then its quads
+		err = eachQuad(ctx, store, quad.Object, f.Type, func(t quad.Quad) error {
+			if t.Predicate != quad.IRI(rdf.Type) {
+				return nil
+			}
+			return eachQuad(ctx, store, quad.Subject, t.Subject, write)
+		})
+	case f.Predicate != "":
+		err = eachQuad(ctx, store, quad.Predicate, f.Predicate, write)
+	default:
+		it := store.QuadsAllIterator()
+		for err == nil && it.Next(ctx) {
+			err = write(store.Quad(it.Result()))
+		}
+		if err == nil {
+			err = it.Err()
+		}
+		it.Close()
+	}
+	if err != nil {
+		return n, err
+	}
+	return n, w.Close()
+}
+
+// eachQuad calls fn with every quad that has v in direction d.
+func eachQuad(ctx context.Context, store *cayley.Handle, d quad.Direction, v quad.Value, fn func(quad.Quad) error) error {
+	ref := store.ValueOf(v)
+	if ref == nil {
+		return nil // not in the store
+	}
+
+	it := store.QuadIterator(d, ref)
+	defer it.Close()
+
+	for it.Next(ctx) {
+		if err := fn(store.Quad(it.Result())); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// term is how a value is shown in CSV and tables: IRIs and strings without
+// brackets and quotes, other values in their N-Quads form.
+func term(v quad.Value) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case quad.IRI:
+		return string(v)
+	case quad.String:
+		return string(v)
+	}
+	return v.String()
+}
+
+// jsonQuad is a line of NDJSON. Values are in their N-Quads form, ex:
+// <Clinic> or "Healthy Life", so IRIs and strings can be told apart.
+type jsonQuad struct {
+	Subject   string `json:"subject"`
+	Predicate string `json:"predicate"`
+	Object    string `json:"object"`
+	Label     string `json:"label,omitempty"`
+}
+
+type jsonWriter struct {
+	enc *json.Encoder
+}
+
+func (w *jsonWriter) WriteQuad(q quad.Quad) error {
+	jq := jsonQuad{
+		Subject:   q.Subject.String(),
+		Predicate: q.Predicate.String(),
+		Object:    q.Object.String(),
+	}
+	if q.Label != nil {
+		jq.Label = q.Label.String()
+	}
+	return w.enc.Encode(jq)
+}
+
+func (w *jsonWriter) Close() error { return nil }
+
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (w *csvWriter) WriteQuad(q quad.Quad) error {
+	if !w.header {
+		w.header = true
+		if err := w.w.Write([]string{"subject", "predicate", "object", "label"}); err != nil {
+			return err
+		}
+	}
+	return w.w.Write([]string{term(q.Subject), term(q.Predicate), term(q.Object), term(q.Label)})
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// tableBlock is how many rows are aligned together. A tabwriter keeps
+// everything in memory until it is flushed, so long dumps are aligned in
+// blocks.
+const tableBlock = 500
+
+type tableWriter struct {
+	tw   *tabwriter.Writer
+	rows int
+}
+
+func newTableWriter(w io.Writer) *tableWriter {
+	return &tableWriter{tw: tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)}
+}
+
+func (w *tableWriter) WriteQuad(q quad.Quad) error {
+	if w.rows%tableBlock == 0 {
+		if w.rows > 0 {
+			if err := w.tw.Flush(); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintln(w.tw, "SUBJECT\tPREDICATE\tOBJECT\tLABEL")
+	}
+	w.rows++
+	_, err := fmt.Fprintf(w.tw, "%s\t%s\t%s\t%s\n", term(q.Subject), term(q.Predicate), term(q.Object), term(q.Label))
+	return err
+}
+
+func (w *tableWriter) Close() error { return w.tw.Flush() }
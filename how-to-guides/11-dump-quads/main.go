@@ -0,0 +1,153 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"context"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	_ "github.com/cayleygraph/cayley/graph/kv/bolt"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	uuid "github.com/satori/go.uuid"
+)
+
+var dbPath = "db.boltdb"
+
+type Admin struct {
+	Name           string `json:"name" quad:"name"`
+	Email          string `json:"email" quad:"email"`
+	HashedPassword string `json:"hashedPassword"  quad:"hashed_password"`
+}
+
+type Clinic struct {
+	Name      string         `json:"name" quad:"name"`
+	Address1  string         `json:"address" quad:"address"`
+	CreatedBy quad.IRI       `quad:"createdBy"`
+	OfficeTel string         `json:"officeTel" quad:"officeTel"`
+	Hours     []OpeningHours `quad:"schema:openingHoursSpecification"`
+}
+
+type OpeningHours struct {
+	DayOfWeek quad.IRI `json:"day" quad:"schema:dayOfWeek"`
+	Slot      int      `json:"slot" quad:"slot"`
+	Opens     string   `json:"opens" quad:"schema:opens"` // ex: 12:00 or 12:00:00
+	Closes    string   `json:"closes" quad:"schema:closes"`
+}
+
+func init() {
+	schema.RegisterType("Admin", Admin{})
+	schema.RegisterType("Clinic", Clinic{})
+	schema.RegisterType("schema:OpeningHoursSpecification", OpeningHours{})
+	schema.GenerateID = func(_ interface{}) quad.Value {
+		return quad.IRI(uuid.NewV1().String())
+	}
+}
+
+func main() {
+	db := flag.String("db", "", "bolt database to dump; a new one with sample data is created when empty")
+	format := flag.String("format", "table", strings.Join(Formats, ", "))
+	subject := flag.String("subject", "", "dump only the quads of the node with this IRI")
+	predicate := flag.String("predicate", "", "dump only the quads with this predicate, ex: name")
+	typ := flag.String("type", "", "dump only the quads of nodes of this rdf:type, ex: Clinic")
+	flag.Parse()
+
+	var store *cayley.Handle
+	if *db == "" {
+		os.RemoveAll(dbPath)
+		store = initializeAndOpenGraph(dbPath)
+		insertSampleData(store)
+	} else {
+		store = initializeAndOpenGraph(*db)
+	}
+	defer store.Close()
+
+	var f Filter
+	if *subject != "" {
+		f.Subject = quad.IRI(*subject)
+	}
+	f.Predicate = quad.IRI(*predicate)
+	f.Type = quad.IRI(*typ)
+
+	w, err := NewQuadWriter(os.Stdout, *format)
+	checkErr(err)
+
+	n, err := Dump(context.TODO(), store, w, f)
+	checkErr(err)
+	fmt.Fprintf(os.Stderr, "%d quads\n", n)
+}
+
+func checkErr(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func initializeAndOpenGraph(dbFile string) *cayley.Handle {
+	graph.InitQuadStore("bolt", dbFile, nil)
+
+	// Open and use the database
+	store, err := cayley.NewGraph("bolt", dbFile, nil)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return store
+}
+
+// insertSampleData creates the admin and the clinic of the first guide,
+// with opening hours.
+func insertSampleData(store *cayley.Handle) {
+	a := Admin{
+		Name:           "Josh",
+		Email:          "josh_f@gmail.com",
+		HashedPassword: "435iue8uou9eu",
+	}
+
+	_, err := insert(store, a)
+	checkErr(err)
+
+	adminId, err := findAdminID(store, a.Email)
+	checkErr(err)
+
+	c := Clinic{
+		Name:      "Healthy Life",
+		Address1:  "11 boar st, Singapore 11233",
+		CreatedBy: adminId,
+		OfficeTel: "65 6100 0939",
+		Hours: []OpeningHours{
+			{DayOfWeek: quad.IRI("schema:Monday"), Slot: 1, Opens: "08:00", Closes: "12:00"},
+			{DayOfWeek: quad.IRI("schema:Monday"), Slot: 2, Opens: "13:00", Closes: "18:00"},
+		},
+	}
+
+	_, err = insert(store, c)
+	checkErr(err)
+}
+
+func insert(h *cayley.Handle, o interface{}) (quad.Value, error) {
+	qw := graph.NewWriter(h)
+	id, err := schema.WriteAsQuads(qw, o)
+	// don't forget to close a writer; it has some internal buffering, and
+	// writing what is left of it can fail too
+	if cerr := qw.Close(); err == nil {
+		err = cerr
+	}
+	return id, err
+}
+
+func findAdminID(store *cayley.Handle, email string) (quad.IRI, error) {
+	p := cayley.StartPath(store).Has(quad.IRI("email"), quad.String(email))
+	id, err := p.Iterate(nil).FirstValue(nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	return id.(quad.IRI), nil
+}
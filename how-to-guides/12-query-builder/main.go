@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"context"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	_ "github.com/cayleygraph/cayley/graph/kv/bolt"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	uuid "github.com/satori/go.uuid"
+)
+
+var dbPath = "db.boltdb"
+
+type Admin struct {
+	Name           string `json:"name" quad:"name"`
+	Email          string `json:"email" quad:"email"`
+	HashedPassword string `json:"hashedPassword"  quad:"hashed_password"`
+}
+
+type Clinic struct {
+	ID        quad.IRI       `json:"id" quad:"@id"`
+	Name      string         `json:"name" quad:"name"`
+	Address1  string         `json:"address" quad:"address"`
+	CreatedBy quad.IRI       `quad:"createdBy"`
+	OfficeTel string         `json:"officeTel" quad:"officeTel,optional"`
+	Hours     []OpeningHours `quad:"schema:openingHoursSpecification"`
+}
+
+type OpeningHours struct {
+	DayOfWeek quad.IRI `json:"day" quad:"schema:dayOfWeek"`
+	Slot      int      `json:"slot" quad:"slot"`
+	Opens     string   `json:"opens" quad:"schema:opens"` // ex: 12:00 or 12:00:00
+	Closes    string   `json:"closes" quad:"schema:closes"`
+}
+
+func init() {
+	schema.RegisterType("Admin", Admin{})
+	schema.RegisterType("Clinic", Clinic{})
+	schema.RegisterType("schema:OpeningHoursSpecification", OpeningHours{})
+	schema.GenerateID = func(_ interface{}) quad.Value {
+		return quad.IRI(uuid.NewV1().String())
+	}
+}
+
+func main() {
+	os.RemoveAll(dbPath)
+	store := initializeAndOpenGraph(dbPath)
+	ctx := context.TODO()
+
+	josh := Admin{Name: "Josh", Email: "josh_f@gmail.com", HashedPassword: "435iue8uou9eu"}
+	mary := Admin{Name: "Mary", Email: "mary@clinics.sg", HashedPassword: "98ueoa7ou3u"}
+	for _, a := range []Admin{josh, mary} {
+		_, err := insert(store, a)
+		checkErr(err)
+	}
+
+	joshId, err := findAdminID(store, josh.Email)
+	checkErr(err)
+	maryId, err := findAdminID(store, mary.Email)
+	checkErr(err)
+
+	clinics := []Clinic{
+		{
+			Name:      "Heal Now",
+			Address1:  "3234 Rot Road, Singapore",
+			CreatedBy: joshId,
+			OfficeTel: "65 6100 0939",
+			Hours: []OpeningHours{
+				{DayOfWeek: "schema:Monday", Slot: 1, Opens: "08:00", Closes: "12:00"},
+				{DayOfWeek: "schema:Monday", Slot: 2, Opens: "13:00", Closes: "18:00"},
+			},
+		},
+		{
+			Name:      "Healthy Life",
+			Address1:  "11 boar st, Singapore 11233",
+			CreatedBy: joshId,
+			Hours: []OpeningHours{
+				{DayOfWeek: "schema:Tuesday", Slot: 1, Opens: "09:00", Closes: "17:00"},
+			},
+		},
+		{
+			Name:      "Raffles Medical",
+			Address1:  "585 North Bridge Road, Singapore 188770",
+			CreatedBy: maryId,
+			OfficeTel: "65 6311 1111",
+			Hours: []OpeningHours{
+				{DayOfWeek: "schema:Monday", Slot: 1, Opens: "08:00", Closes: "18:00"},
+			},
+		},
+	}
+	for _, c := range clinics {
+		// an ID field is written as it is, so every clinic without one would be <>
+		c.ID = quad.IRI(uuid.NewV1().String())
+		_, err := insert(store, c)
+		checkErr(err)
+	}
+
+	run(ctx, store, "Created by Josh", NewClinicQuery().CreatedBy(josh.Email))
+	run(ctx, store, "Name starts with Heal, open on Monday", NewClinicQuery().NameHasPrefix("Heal").OpenOn("schema:Monday"))
+	run(ctx, store, "Open on Monday at 12:30, with an office phone", NewClinicQuery().OpenAt("schema:Monday", "12:30").HasOfficeTel())
+
+	c, err := NewClinicQuery().NameIs("Healthy Life").First(ctx, store)
+	checkErr(err)
+	if c != nil {
+		fmt.Println("First:", c.Name+",", c.Address1)
+	}
+}
+
+// run prints the path of a query and the clinics it finds.
+func run(ctx context.Context, store *cayley.Handle, title string, q *ClinicQuery) {
+	fmt.Println(title + ":")
+	fmt.Println("-------")
+	fmt.Print(q.Explain())
+	fmt.Println()
+
+	clinics, err := q.All(ctx, store)
+	checkErr(err)
+	for _, c := range clinics {
+		fmt.Println(c.Name+",", c.Address1)
+	}
+
+	fmt.Println()
+}
+
+func checkErr(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func initializeAndOpenGraph(dbFile string) *cayley.Handle {
+	graph.InitQuadStore("bolt", dbFile, nil)
+
+	// Open and use the database
+	store, err := cayley.NewGraph("bolt", dbFile, nil)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return store
+}
+
+func insert(h *cayley.Handle, o interface{}) (quad.Value, error) {
+	qw := graph.NewWriter(h)
+	id, err := schema.WriteAsQuads(qw, o)
+	// don't forget to close a writer; it has some internal buffering, and
+	// writing what is left of it can fail too
+	if cerr := qw.Close(); err == nil {
+		err = cerr
+	}
+	return id, err
+}
+
+func findAdminID(store *cayley.Handle, email string) (quad.IRI, error) {
+	p := cayley.StartPath(store).Has(quad.IRI("email"), quad.String(email))
+	id, err := p.Iterate(nil).FirstValue(nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	return id.(quad.IRI), nil
+}
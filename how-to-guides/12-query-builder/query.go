@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/graph/path"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	"github.com/cayleygraph/cayley/voc/rdf"
+)
+
+// How selective a step is. Steps are compiled from the most selective to
+// the least, so the store has as few clinics as possible to check with the
+// slow ones.
+const (
+	exact  = iota // one value, looked up in the index of the store
+	linked        // a value a few hops away
+	scan          // a comparison, every value has to be checked
+)
+
+// step is one constraint of a query.
+type step struct {
+	cost  int
+	gizmo func(tag string) string // the same step in Gizmo, for Explain
+	apply func(p *path.Path, tag string) *path.Path
+}
+
+// ClinicQuery selects clinics. Build it with NewClinicQuery and its
+// methods, ex:
+//
+//	q := NewClinicQuery().CreatedBy("josh_f@gmail.com").OpenOn("schema:Monday")
+//	clinics, err := q.All(ctx, store)
+type ClinicQuery struct {
+	steps []step
+	limit int64
+}
+
+// NewClinicQuery returns a query for every clinic.
+func NewClinicQuery() *ClinicQuery {
+	return &ClinicQuery{}
+}
+
+func (q *ClinicQuery) add(s step) *ClinicQuery {
+	q.steps = append(q.steps, s)
+	return q
+}
+
+// NameIs keeps the clinic with this exact name.
+func (q *ClinicQuery) NameIs(name string) *ClinicQuery {
+	return q.add(step{
+		cost: exact,
+		gizmo: func(string) string {
+			return fmt.Sprintf(".Has(%s, %s)", js("<name>"), js(name))
+		},
+		apply: func(p *path.Path, _ string) *path.Path {
+			return p.Has(quad.IRI("name"), quad.String(name))
+		},
+	})
+}
+
+// NameHasPrefix keeps the clinics whose name starts with prefix. It is case
+// sensitive.
+func (q *ClinicQuery) NameHasPrefix(prefix string) *ClinicQuery {
+	// every name with the prefix sorts between the prefix and the prefix
+	// followed by the largest rune
+	upper := prefix + string('\U0010FFFF')
+	return q.add(step{
+		cost: scan,
+		gizmo: func(tag string) string {
+			return fmt.Sprintf(".Tag(%[1]s).Out(%s).Filter(gte(%s), lt(%s)).Back(%[1]s)", js(tag), js("<name>"), js(prefix), js(upper))
+		},
+		apply: func(p *path.Path, tag string) *path.Path {
+			return p.Tag(tag).
+				Out(quad.IRI("name")).
+				Filter(iterator.CompareGTE, quad.String(prefix)).
+				Filter(iterator.CompareLT, quad.String(upper)).
+				Back(tag)
+		},
+	})
+}
+
+// CreatedBy keeps the clinics created by the admin with this email.
+func (q *ClinicQuery) CreatedBy(adminEmail string) *ClinicQuery {
+	return q.add(step{
+		cost: linked,
+		gizmo: func(tag string) string {
+			return fmt.Sprintf(".Tag(%[1]s).Out(%s).Has(%s, %s).Back(%[1]s)", js(tag), js("<createdBy>"), js("<email>"), js(adminEmail))
+		},
+		apply: func(p *path.Path, tag string) *path.Path {
+			return p.Tag(tag).
+				Out(quad.IRI("createdBy")).
+				Has(quad.IRI("email"), quad.String(adminEmail)).
+				Back(tag)
+		},
+	})
+}
+
+// OpenOn keeps the clinics that have opening hours on a day, ex:
+// schema:Monday.
+func (q *ClinicQuery) OpenOn(day quad.IRI) *ClinicQuery {
+	return q.add(step{
+		cost: linked,
+		gizmo: func(tag string) string {
+			return fmt.Sprintf(".Tag(%[1]s).Out(%s).Has(%s, %s).Back(%[1]s)", js(tag), js("<schema:openingHoursSpecification>"), js("<schema:dayOfWeek>"), js(iri(day)))
+		},
+		apply: func(p *path.Path, tag string) *path.Path {
+			return p.Tag(tag).
+				Out(quad.IRI("schema:openingHoursSpecification")).
+				Has(quad.IRI("schema:dayOfWeek"), day).
+				Back(tag)
+		},
+	})
+}
+
+// OpenAt keeps the clinics that are open on a day at a time, ex:
+// OpenAt("schema:Monday", "09:30"). Times are compared as strings, so they
+// must have two digits for the hour, like the opening hours.
+func (q *ClinicQuery) OpenAt(day quad.IRI, hhmm string) *ClinicQuery {
+	return q.add(step{
+		cost: scan,
+		gizmo: func(tag string) string {
+			return fmt.Sprintf(".Tag(%[1]s).Out(%[3]s).Has(%[4]s, %[5]s).Tag(%[2]s).Out(%[6]s).Filter(lte(%[8]s)).Back(%[2]s).Out(%[7]s).Filter(gt(%[8]s)).Back(%[1]s)",
+				js(tag), js(tag+"_hours"), js("<schema:openingHoursSpecification>"), js("<schema:dayOfWeek>"), js(iri(day)), js("<schema:opens>"), js("<schema:closes>"), js(hhmm))
+		},
+		apply: func(p *path.Path, tag string) *path.Path {
+			hours := tag + "_hours"
+			return p.Tag(tag).
+				Out(quad.IRI("schema:openingHoursSpecification")).
+				Has(quad.IRI("schema:dayOfWeek"), day).
+				Tag(hours).
+				Out(quad.IRI("schema:opens")).
+				Filter(iterator.CompareLTE, quad.String(hhmm)).
+				Back(hours).
+				Out(quad.IRI("schema:closes")).
+				Filter(iterator.CompareGT, quad.String(hhmm)).
+				Back(tag)
+		},
+	})
+}
+
+// HasOfficeTel keeps the clinics that have an office phone number.
+func (q *ClinicQuery) HasOfficeTel() *ClinicQuery {
+	return q.add(step{
+		cost: scan,
+		gizmo: func(tag string) string {
+			return fmt.Sprintf(".Tag(%[1]s).Out(%s).Filter(gt(\"\")).Back(%[1]s)", js(tag), js("<officeTel>"))
+		},
+		apply: func(p *path.Path, tag string) *path.Path {
+			return p.Tag(tag).
+				Out(quad.IRI("officeTel")).
+				Filter(iterator.CompareGT, quad.String("")).
+				Back(tag)
+		},
+	})
+}
+
+// Limit returns at most n clinics.
+func (q *ClinicQuery) Limit(n int) *ClinicQuery {
+	q.limit = int64(n)
+	return q
+}
+
+// ordered returns the steps, most selective first. Steps of the same cost
+// keep the order they were added in.
+func (q *ClinicQuery) ordered() []step {
+	steps := append([]step(nil), q.steps...)
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].cost < steps[j].cost })
+	return steps
+}
+
+// iri writes an IRI the way Gizmo reads it.
+func iri(v quad.IRI) string {
+	return "<" + string(v) + ">"
+}
+
+// js writes a string the way JavaScript, and so Gizmo, reads it. %q is for
+// Go: it writes escapes, ex: \U0010ffff, that JavaScript doesn't know.
+func js(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case unicode.IsPrint(r):
+			b.WriteRune(r)
+		case r > 0xffff:
+			// a surrogate pair, strings are UTF-16 in JavaScript
+			r1, r2 := utf16.EncodeRune(r)
+			fmt.Fprintf(&b, `\u%04x\u%04x`, r1, r2)
+		default:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// stepTag is the tag a step uses to come back to the clinic after looking
+// at its properties.
+func stepTag(i int) string {
+	return fmt.Sprintf("step%d", i)
+}
+
+// Path compiles the query to a cayley path over the ids of the clinics.
+func (q *ClinicQuery) Path(store *cayley.Handle) *path.Path {
+	p := cayley.StartPath(store).Has(quad.IRI(rdf.Type), quad.IRI("Clinic"))
+	for i, s := range q.ordered() {
+		p = s.apply(p, stepTag(i))
+	}
+	// a clinic open twice on Monday would be found twice
+	p = p.Unique()
+	if q.limit > 0 {
+		p = p.Limit(q.limit)
+	}
+	return p
+}
+
+// Explain returns the path of the query written in Gizmo, one step per
+// line, ex: to paste it into the query editor of cayley http.
+func (q *ClinicQuery) Explain() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "g.V().Has(%s, %s)\n", js(iri(rdf.Type)), js("<Clinic>"))
+	for i, s := range q.ordered() {
+		fmt.Fprintf(&b, "  %s\n", s.gizmo(stepTag(i)))
+	}
+	b.WriteString("  .Unique()")
+	if q.limit > 0 {
+		fmt.Fprintf(&b, ".Limit(%d)", q.limit)
+	}
+	b.WriteString(".All()\n")
+	return b.String()
+}
+
+// IDs returns the ids of the clinics of the query.
+func (q *ClinicQuery) IDs(ctx context.Context, store *cayley.Handle) ([]quad.Value, error) {
+	return q.Path(store).Iterate(ctx).AllValues(nil)
+}
+
+// All returns the clinics of the query.
+func (q *ClinicQuery) All(ctx context.Context, store *cayley.Handle) ([]Clinic, error) {
+	ids, err := q.IDs(ctx, store)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	var clinics []Clinic
+	if err := schema.LoadTo(ctx, store, &clinics, ids...); err != nil {
+		return nil, err
+	}
+	return clinics, nil
+}
+
+// First returns the first clinic of the query, or nil if there is none.
+func (q *ClinicQuery) First(ctx context.Context, store *cayley.Handle) (*Clinic, error) {
+	id, err := q.Path(store).Iterate(ctx).FirstValue(nil)
+	if err != nil || id == nil {
+		return nil, err
+	}
+
+	var c Clinic
+	if err := schema.LoadTo(ctx, store, &c, id); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
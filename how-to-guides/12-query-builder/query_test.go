@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/query"
+	"github.com/cayleygraph/cayley/query/gizmo"
+	"github.com/cayleygraph/cayley/schema"
+	"github.com/cayleygraph/cayley/voc/rdf"
+)
+
+// testStore returns a store in memory with the clinics of two admins.
+func testStore(t *testing.T) *cayley.Handle {
+	store, err := cayley.NewMemoryGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	joshID := quad.IRI("831bc569-43eb-11e7-9cd0-843a4b0f5a10")
+	clinics := []Clinic{
+		{
+			ID:        "831c71de-43eb-11e7-9cd0-843a4b0f5a10",
+			Name:      "Heal Now",
+			Address1:  "3234 Rot Road, Singapore",
+			CreatedBy: joshID,
+			OfficeTel: "65 6100 0939",
+			Hours: []OpeningHours{
+				{DayOfWeek: "schema:Monday", Slot: 1, Opens: "08:00", Closes: "12:00"},
+				{DayOfWeek: "schema:Monday", Slot: 2, Opens: "13:00", Closes: "18:00"},
+			},
+		},
+		{
+			ID:        "831c9a2f-43eb-11e7-9cd0-843a4b0f5a10",
+			Name:      "Healthy Life",
+			Address1:  "11 boar st, Singapore 11233",
+			CreatedBy: joshID,
+			Hours: []OpeningHours{
+				{DayOfWeek: "schema:Tuesday", Slot: 1, Opens: "09:00", Closes: "17:00"},
+			},
+		},
+		{
+			ID:        "831cc1a4-43eb-11e7-9cd0-843a4b0f5a10",
+			Name:      "Raffles Medical",
+			Address1:  "585 North Bridge Road, Singapore 188770",
+			CreatedBy: "831bd2e7-43eb-11e7-9cd0-843a4b0f5a10",
+			OfficeTel: "65 6311 1111",
+			Hours: []OpeningHours{
+				{DayOfWeek: "schema:Monday", Slot: 1, Opens: "08:00", Closes: "18:00"},
+			},
+		},
+	}
+
+	qw := graph.NewWriter(store)
+	// an Admin has no ID field, so it would get a new ID every run
+	qw.WriteQuad(quad.Make(joshID, quad.IRI(rdf.Type), quad.IRI("Admin"), nil))
+	qw.WriteQuad(quad.Make(joshID, quad.IRI("email"), "josh_f@gmail.com", nil))
+	for _, c := range clinics {
+		if _, err := schema.WriteAsQuads(qw, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := qw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// runGizmo runs a Gizmo query and returns the ids it finds, sorted.
+func runGizmo(ctx context.Context, store *cayley.Handle, text string) ([]string, error) {
+	it, err := gizmo.NewSession(store).Execute(ctx, text, query.Options{Collation: query.Raw})
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	var ids []string
+	for it.Next(ctx) {
+		r := it.Result().(*gizmo.Result)
+		if r.Meta {
+			continue
+		}
+		ids = append(ids, quad.StringOf(store.NameOf(r.Tags["id"])))
+	}
+	sort.Strings(ids)
+	return ids, it.Err()
+}
+
+// TestExplain checks that the Gizmo of Explain finds the clinics the path
+// of the query finds.
+func TestExplain(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+	for _, q := range []*ClinicQuery{
+		NewClinicQuery(),
+		NewClinicQuery().NameIs("Heal Now"),
+		NewClinicQuery().NameHasPrefix("Heal"),
+		NewClinicQuery().NameHasPrefix("Heal").OpenOn("schema:Monday"),
+		NewClinicQuery().CreatedBy("josh_f@gmail.com"),
+		NewClinicQuery().OpenAt("schema:Monday", "12:30").HasOfficeTel(),
+		NewClinicQuery().HasOfficeTel().Limit(1),
+	} {
+		ids, err := q.IDs(ctx, store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want []string
+		for _, id := range ids {
+			want = append(want, quad.StringOf(id))
+		}
+		sort.Strings(want)
+
+		text := q.Explain()
+		got, err := runGizmo(ctx, store, text)
+		if err != nil {
+			t.Errorf("%s: %v", text, err)
+			continue
+		}
+		if len(want) == 0 || len(got) != len(want) {
+			t.Errorf("%s: found %v, want %v", text, got, want)
+			continue
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: found %v, want %v", text, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestJS(t *testing.T) {
+	for s, want := range map[string]string{
+		"Heal":           `"Heal"`,
+		`say "hi" \ bye`: `"say \"hi\" \\ bye"`,
+		"Café":           `"Café"`,
+		"line\nbreak":    `"line\u000abreak"`,
+		"Heal\U0010FFFF": `"Heal\udbff\udfff"`,
+	} {
+		if got := js(s); got != want {
+			t.Errorf("js(%q) = %s, want %s", s, got, want)
+		}
+	}
+}
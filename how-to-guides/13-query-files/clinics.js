@@ -0,0 +1,6 @@
+// every clinic with its name and address
+g.V("<Clinic>")
+  .In("<rdf:type>")
+  .Save("<name>", "name")
+  .Save("<address>", "address")
+  .All()
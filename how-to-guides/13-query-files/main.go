@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"github.com/cayleygraph/cayley"
+	_ "github.com/cayleygraph/cayley/graph/kv/bolt"
+)
+
+func main() {
+	db := flag.String("db", "../05-update-clinic/db.boltdb", "bolt database to query")
+	lang := flag.String("lang", "", "gizmo, mql or graphql; taken from the extension of the query file when empty")
+	format := flag.String("format", "json", "json or table")
+	timeout := flag.Duration("timeout", 10*time.Second, "stop the query after this long, 0 for no limit")
+	limit := flag.Int("limit", 100, "maximum number of results, 0 for no limit")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: go run *.go [flags] <query file>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	file := flag.Arg(0)
+
+	if *lang == "" {
+		var err error
+		*lang, err = languageOf(file)
+		checkErr(err)
+	}
+
+	text, err := ioutil.ReadFile(file)
+	checkErr(err)
+
+	// the store has to exist, we don't want to create an empty one because
+	// of a typo
+	_, err = os.Stat(*db)
+	checkErr(err)
+
+	store, err := cayley.NewGraph("bolt", *db, nil)
+	checkErr(err)
+	defer store.Close()
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	results, err := Run(ctx, store, *lang, string(text), *limit)
+	if err == context.DeadlineExceeded {
+		err = fmt.Errorf("query timed out after %v, %d results so far", *timeout, len(results))
+	}
+	checkErr(err)
+
+	switch *format {
+	case "json":
+		err = WriteJSON(os.Stdout, results)
+	case "table":
+		err = WriteTable(os.Stdout, results)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	checkErr(err)
+
+	if *limit > 0 && len(results) == *limit {
+		fmt.Fprintf(os.Stderr, "stopped at %d results, use -limit to see more\n", *limit)
+	}
+}
+
+func checkErr(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
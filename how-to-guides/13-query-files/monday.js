@@ -0,0 +1,10 @@
+// the opening hours of every clinic on Monday; the update guide writes days
+// as in its clinic.json, ex: mon, and the later guides as schema:Monday
+g.V("<Clinic>")
+  .In("<rdf:type>")
+  .Save("<name>", "clinic")
+  .Out("<schema:openingHoursSpecification>")
+  .Has("<schema:dayOfWeek>", "<mon>")
+  .Save("<schema:opens>", "opens")
+  .Save("<schema:closes>", "closes")
+  .All()
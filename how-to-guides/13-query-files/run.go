@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/query"
+	_ "github.com/cayleygraph/cayley/query/gizmo"
+	_ "github.com/cayleygraph/cayley/query/graphql"
+	_ "github.com/cayleygraph/cayley/query/mql"
+)
+
+// languages maps the extension of a query file to its language.
+var languages = map[string]string{
+	".js":      "gizmo",
+	".gizmo":   "gizmo",
+	".mql":     "mql",
+	".json":    "mql",
+	".graphql": "graphql",
+	".gql":     "graphql",
+}
+
+// languageOf returns the language of a query file from its extension.
+func languageOf(file string) (string, error) {
+	lang, ok := languages[strings.ToLower(filepath.Ext(file))]
+	if !ok {
+		return "", fmt.Errorf("can't tell the language of %s from its extension, use -lang", file)
+	}
+	return lang, nil
+}
+
+// Run executes a query and returns at most limit results, as JSON values:
+// maps, slices, strings and numbers.
+func Run(ctx context.Context, qs graph.QuadStore, lang, text string, limit int) ([]interface{}, error) {
+	l := query.GetLanguage(lang)
+	if l == nil || l.Session == nil {
+		return nil, fmt.Errorf("unknown query language %q", lang)
+	}
+
+	it, err := l.Session(qs).Execute(ctx, text, query.Options{
+		Collation: query.JSON,
+		Limit:     limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	var results []interface{}
+	for it.Next(ctx) {
+		results = append(results, it.Result())
+		if limit > 0 && len(results) >= limit {
+			break
+		}
+	}
+	if err := it.Err(); err != nil {
+		return results, err
+	}
+	if lang == "graphql" {
+		results = graphqlNodes(results)
+	}
+	if err := ctx.Err(); err == context.DeadlineExceeded {
+		return results, err
+	}
+	return results, nil
+}
+
+// graphqlNodes returns the nodes of GraphQL results. GraphQL returns one
+// result for the whole query, ex: {"nodes": [...]}, with an object instead
+// of the list when there is only one node; the other languages return one
+// result per node.
+func graphqlNodes(results []interface{}) []interface{} {
+	var nodes []interface{}
+	for _, r := range results {
+		m, ok := r.(map[string]interface{})
+		if !ok {
+			nodes = append(nodes, r)
+			continue
+		}
+		for _, v := range m {
+			if l, ok := v.([]interface{}); ok {
+				nodes = append(nodes, l...)
+			} else {
+				nodes = append(nodes, v)
+			}
+		}
+	}
+	return nodes
+}
+
+// WriteJSON writes the results as an indented JSON array.
+func WriteJSON(w io.Writer, results []interface{}) error {
+	if results == nil {
+		results = []interface{}{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	// IRIs are written as "<name>", not "\u003cname\u003e"
+	enc.SetEscapeHTML(false)
+	return enc.Encode(results)
+}
+
+// WriteTable writes the results as a table, one row per result and one
+// column per key, ex: the tags of a Gizmo query. Results that are not
+// objects get a single "result" column, and nested values are written as
+// JSON.
+func WriteTable(w io.Writer, results []interface{}) error {
+	seen := make(map[string]bool)
+	var columns []string
+	for _, r := range results {
+		m, ok := r.(map[string]interface{})
+		if !ok {
+			m = map[string]interface{}{"result": r}
+		}
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				columns = append(columns, k)
+			}
+		}
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		// the id first, it's what Gizmo returns for every node
+		if (columns[i] == "id") != (columns[j] == "id") {
+			return columns[i] == "id"
+		}
+		return columns[i] < columns[j]
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(columns, "\t")))
+	for _, r := range results {
+		m, ok := r.(map[string]interface{})
+		if !ok {
+			m = map[string]interface{}{"result": r}
+		}
+		cells := make([]string, len(columns))
+		for i, c := range columns {
+			cells[i] = cell(m[c])
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+func cell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case quad.Value:
+		// GraphQL returns values as they are in the store
+		return fmt.Sprint(quad.NativeOf(v))
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
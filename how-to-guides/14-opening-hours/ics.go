@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// icsYears is how many years of time zone changes are written in a
+// calendar, from the year it starts.
+const icsYears = 3
+
+const (
+	icsDate     = "20060102"
+	icsDateTime = "20060102T150405"
+)
+
+// now is the clock of the DTSTAMP of the events, the time the file is
+// written; tests can stop it.
+var now = time.Now
+
+var icsDays = map[time.Weekday]string{
+	time.Monday: "MO", time.Tuesday: "TU", time.Wednesday: "WE", time.Thursday: "TH",
+	time.Friday: "FR", time.Saturday: "SA", time.Sunday: "SU",
+}
+
+// icsWriter writes the lines of an iCalendar file: CRLF line endings and
+// lines folded at 75 bytes, the leading space of continuation lines
+// included, as RFC 5545 wants.
+type icsWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (w *icsWriter) line(format string, args ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	s := fmt.Sprintf(format, args...)
+	max := 75 // the first line; the next ones start with a space
+	for len(s) > max {
+		// don't cut a UTF-8 character in two
+		n := max
+		for n > 0 && s[n]&0xC0 == 0x80 {
+			n--
+		}
+		if _, w.err = w.w.WriteString(s[:n] + "\r\n "); w.err != nil {
+			return
+		}
+		s, max = s[n:], 74
+	}
+	_, w.err = w.w.WriteString(s + "\r\n")
+}
+
+// icsText escapes a text value.
+func icsText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+func icsOffset(seconds int) string {
+	sign := '+'
+	if seconds < 0 {
+		sign, seconds = '-', -seconds
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+// WriteICS writes the opening hours of clinics as an iCalendar file, ex:
+// the clinics of one admin as a single feed. Every slot of the week is a
+// weekly event starting on the first date after from; special hours are
+// events of their own and remove the regular slots of their dates.
+func WriteICS(w io.Writer, name string, schedules []Schedule, from time.Time) error {
+	stamp := now()
+	iw := &icsWriter{w: bufio.NewWriter(w)}
+	iw.line("BEGIN:VCALENDAR")
+	iw.line("VERSION:2.0")
+	iw.line("PRODID:-//cayley-docs//opening hours//EN")
+	iw.line("CALSCALE:GREGORIAN")
+	iw.line("X-WR-CALNAME:%s", icsText(name))
+
+	seen := make(map[string]bool)
+	for _, s := range schedules {
+		if !seen[s.Location.String()] {
+			seen[s.Location.String()] = true
+			writeVTimezone(iw, s.Location, from)
+		}
+	}
+	for _, s := range schedules {
+		writeEvents(iw, s, from, stamp)
+	}
+
+	iw.line("END:VCALENDAR")
+	if iw.err != nil {
+		return iw.err
+	}
+	return iw.w.Flush()
+}
+
+// transition is a change of the UTC offset of a time zone.
+type transition struct {
+	at       time.Time
+	from, to int // offsets in seconds
+}
+
+// transitions finds the changes of the UTC offset of a time zone between
+// two times. The tz database can't be listed, so it looks at every day and
+// then for the minute of the change.
+func transitions(loc *time.Location, start, end time.Time) []transition {
+	var found []transition
+	offset := func(t time.Time) int {
+		_, off := t.In(loc).Zone()
+		return off
+	}
+	for day := start; day.Before(end); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		before, after := offset(day), offset(next)
+		if before == after {
+			continue
+		}
+		lo, hi := day, next // the change is after lo, at or before hi
+		for hi.Sub(lo) > time.Minute {
+			mid := lo.Add(hi.Sub(lo) / 2).Truncate(time.Minute)
+			if mid.Equal(lo) {
+				mid = lo.Add(time.Minute)
+			}
+			if offset(mid) == before {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		found = append(found, transition{at: hi, from: before, to: after})
+	}
+	return found
+}
+
+// writeVTimezone describes a time zone, so calendar apps that don't know
+// the IANA names can still place the events. It lists the actual changes of
+// the years of the calendar.
+func writeVTimezone(w *icsWriter, loc *time.Location, from time.Time) {
+	start := time.Date(from.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(icsYears, 0, 0)
+
+	w.line("BEGIN:VTIMEZONE")
+	w.line("TZID:%s", loc)
+
+	first := start.In(loc)
+	name, off := first.Zone()
+	kind := "STANDARD"
+	if first.IsDST() {
+		kind = "DAYLIGHT"
+	}
+	w.line("BEGIN:%s", kind)
+	w.line("DTSTART:19700101T000000")
+	w.line("TZOFFSETFROM:%s", icsOffset(off))
+	w.line("TZOFFSETTO:%s", icsOffset(off))
+	w.line("TZNAME:%s", name)
+	w.line("END:%s", kind)
+
+	for _, t := range transitions(loc, start, end) {
+		after := t.at.In(loc)
+		name, _ := after.Zone()
+		kind := "STANDARD"
+		if after.IsDST() {
+			kind = "DAYLIGHT"
+		}
+		w.line("BEGIN:%s", kind)
+		// the local time of the change, before the clocks move
+		w.line("DTSTART:%s", t.at.Add(time.Duration(t.from)*time.Second).UTC().Format(icsDateTime))
+		w.line("TZOFFSETFROM:%s", icsOffset(t.from))
+		w.line("TZOFFSETTO:%s", icsOffset(t.to))
+		w.line("TZNAME:%s", name)
+		w.line("END:%s", kind)
+	}
+
+	w.line("END:VTIMEZONE")
+}
+
+// event is a recurring slot of a clinic.
+type event struct {
+	uid      string
+	summary  string
+	first    time.Time // date of the first occurrence
+	interval *Interval // nil for a whole day
+	rrule    string
+	until    time.Time // date of the last occurrence, if rrule ends
+	exdates  []time.Time
+}
+
+func writeEvent(w *icsWriter, s Schedule, e event, stamp time.Time) {
+	w.line("BEGIN:VEVENT")
+	w.line("UID:%s", e.uid)
+	w.line("DTSTAMP:%s", stamp.UTC().Format(icsDateTime+"Z"))
+	w.line("SUMMARY:%s", icsText(e.summary))
+	if s.Address != "" {
+		w.line("LOCATION:%s", icsText(s.Address))
+	}
+
+	tz := s.Location.String()
+	if e.interval == nil {
+		w.line("DTSTART;VALUE=DATE:%s", e.first.Format(icsDate))
+		w.line("DTEND;VALUE=DATE:%s", e.first.AddDate(0, 0, 1).Format(icsDate))
+		w.line("TRANSP:TRANSPARENT")
+	} else {
+		w.line("DTSTART;TZID=%s:%s", tz, clockOn(e.first, e.interval.Opens, s.Location).Format(icsDateTime))
+		w.line("DTEND;TZID=%s:%s", tz, clockOn(e.first, e.interval.Closes, s.Location).Format(icsDateTime))
+	}
+	if e.rrule != "" {
+		rrule := e.rrule
+		switch {
+		case e.until.IsZero():
+		case e.interval == nil:
+			rrule += ";UNTIL=" + e.until.Format(icsDate)
+		default:
+			// the same type as DTSTART, in UTC
+			rrule += ";UNTIL=" + clockOn(e.until, e.interval.Opens, s.Location).UTC().Format(icsDateTime+"Z")
+		}
+		w.line("RRULE:%s", rrule)
+	}
+	for _, d := range e.exdates {
+		if e.interval == nil {
+			w.line("EXDATE;VALUE=DATE:%s", d.Format(icsDate))
+		} else {
+			w.line("EXDATE;TZID=%s:%s", tz, clockOn(d, e.interval.Opens, s.Location).Format(icsDateTime))
+		}
+	}
+	w.line("END:VEVENT")
+}
+
+// datesOf returns the dates an exception is valid on.
+func datesOf(e Exception) []time.Time {
+	var dates []time.Time
+	for d := e.From; !d.After(e.Through); d = d.AddDate(0, 0, 1) {
+		if e.covers(d) {
+			dates = append(dates, d)
+		}
+	}
+	return dates
+}
+
+// writeEvents writes the events of a clinic.
+func writeEvents(w *icsWriter, s Schedule, from, stamp time.Time) {
+	start := dateOf(from.In(s.Location))
+
+	// every date with special hours replaces the regular slots of its day
+	var special []time.Time
+	for _, e := range s.Exceptions {
+		special = append(special, datesOf(e)...)
+	}
+	sort.Slice(special, func(i, j int) bool { return special[i].Before(special[j]) })
+
+	for _, d := range s.Days {
+		first := start.AddDate(0, 0, (int(d.Weekday)-int(start.Weekday())+7)%7)
+		var exdates []time.Time
+		for _, date := range special {
+			if date.Weekday() == d.Weekday && !date.Before(first) && (len(exdates) == 0 || !date.Equal(exdates[len(exdates)-1])) {
+				exdates = append(exdates, date)
+			}
+		}
+		for _, iv := range d.Open {
+			iv := iv
+			writeEvent(w, s, event{
+				uid:      fmt.Sprintf("%s-%s-%s@clinics", string(s.ID), icsDays[d.Weekday], strings.Replace(iv.Opens.String(), ":", "", -1)),
+				summary:  s.Clinic + " open",
+				first:    first,
+				interval: &iv,
+				rrule:    "FREQ=WEEKLY;BYDAY=" + icsDays[d.Weekday],
+				exdates:  exdates,
+			}, stamp)
+		}
+	}
+
+	for i := range s.Exceptions {
+		e := &s.Exceptions[i]
+		if e.Through.Before(start) {
+			continue // over
+		}
+
+		// the dates where the exception is valid and not hidden by a
+		// shorter one
+		var dates, hidden []time.Time
+		for _, date := range datesOf(*e) {
+			if date.Before(start) {
+				continue
+			}
+			dates = append(dates, date)
+			if s.exceptionOn(date) != e {
+				hidden = append(hidden, date)
+			}
+		}
+		if len(dates) == 0 {
+			continue
+		}
+
+		rrule := ""
+		if len(dates) > 1 {
+			if e.Weekday != nil {
+				rrule = "FREQ=WEEKLY;BYDAY=" + icsDays[*e.Weekday]
+			} else {
+				rrule = "FREQ=DAILY"
+			}
+		}
+
+		summary := s.Clinic + " open"
+		if e.Closed() {
+			summary = s.Clinic + " closed"
+		}
+		if e.Description != "" {
+			summary += " (" + e.Description + ")"
+		}
+
+		ev := event{
+			summary: summary,
+			first:   dates[0],
+			rrule:   rrule,
+			until:   dates[len(dates)-1],
+			exdates: hidden,
+		}
+		// exceptions can start on the same date, ex: a week of short
+		// hours and a closure on its first day
+		uid := fmt.Sprintf("%s-special-%s-%s", string(s.ID), e.From.Format(icsDate), e.Through.Format(icsDate))
+		if e.Weekday != nil {
+			uid += "-" + icsDays[*e.Weekday]
+		}
+		if e.Closed() {
+			ev.uid = uid + "@clinics"
+			writeEvent(w, s, ev, stamp)
+			continue
+		}
+		for _, iv := range e.Open {
+			iv := iv
+			ev.uid = fmt.Sprintf("%s-%s@clinics", uid, strings.Replace(iv.Opens.String(), ":", "", -1))
+			ev.interval = &iv
+			writeEvent(w, s, ev, stamp)
+		}
+	}
+}
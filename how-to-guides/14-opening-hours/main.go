@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"context"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	_ "github.com/cayleygraph/cayley/graph/kv/bolt"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	uuid "github.com/satori/go.uuid"
+)
+
+var dbPath = "db.boltdb"
+
+type Admin struct {
+	Name           string `json:"name" quad:"name"`
+	Email          string `json:"email" quad:"email"`
+	HashedPassword string `json:"hashedPassword"  quad:"hashed_password"`
+}
+
+type Clinic struct {
+	ID        quad.IRI       `json:"id" quad:"@id"`
+	Name      string         `json:"name" quad:"name"`
+	Address1  string         `json:"address" quad:"address"`
+	CreatedBy quad.IRI       `quad:"createdBy"`
+	OfficeTel string         `json:"officeTel" quad:"officeTel"`
+	TimeZone  string         `json:"timeZone" quad:"timeZone,optional"` // IANA name, ex: Asia/Singapore; DefaultTimeZone when empty
+	Hours     []OpeningHours `json:"hours" quad:"schema:openingHoursSpecification"`
+	Special   []OpeningHours `json:"specialHours" quad:"schema:specialOpeningHoursSpecification,optional"` // ex: public holidays
+}
+
+// OpeningHours are the times a clinic opens and closes, then what they apply
+// to: a day and a slot for the weekly hours, dates for special hours.
+type OpeningHours struct {
+	Opens     string   `json:"opens" quad:"schema:opens"` // ex: 12:00 or 12:00:00
+	Closes    string   `json:"closes" quad:"schema:closes"`
+	DayOfWeek quad.IRI `json:"day" quad:"schema:dayOfWeek,optional"` // special hours can be valid every day
+	Slot      int      `json:"slot" quad:"slot,optional"`            // special hours for a whole day have none
+
+	// Special hours only: the dates they are valid for, ex: 2017-12-25, and
+	// why. A clinic that opens and closes at 00:00 is closed.
+	ValidFrom    string `json:"validFrom,omitempty" quad:"schema:validFrom,optional"`
+	ValidThrough string `json:"validThrough,omitempty" quad:"schema:validThrough,optional"`
+	Description  string `json:"description,omitempty" quad:"schema:description,optional"`
+}
+
+func init() {
+	schema.RegisterType("Admin", Admin{})
+	schema.RegisterType("Clinic", Clinic{})
+	schema.RegisterType("schema:OpeningHoursSpecification", OpeningHours{})
+	schema.GenerateID = func(_ interface{}) quad.Value {
+		return quad.IRI(uuid.NewV1().String())
+	}
+}
+
+func main() {
+	db := flag.String("db", "", "bolt database to report on; a new one with sample data is created when empty")
+	format := flag.String("format", "text", strings.Join(Formats, ", "))
+	out := flag.String("o", "-", "output file, - for stdout")
+	weekOf := flag.String("week", "", "report the week of this date, ex: 2017-12-25, with its special hours")
+	openAt := flag.String("open-at", "", "tell which clinics are open at this time, ex: now or 2017-12-25T10:00+08:00, instead of the report")
+	ics := flag.String("ics", "", "write the calendar of the clinic with this name as iCalendar, instead of the report")
+	icsAdmin := flag.String("ics-admin", "", "write the calendar of all clinics created by the admin with this email as iCalendar, instead of the report")
+	flag.Parse()
+
+	var store *cayley.Handle
+	if *db == "" {
+		os.RemoveAll(dbPath)
+		store = initializeAndOpenGraph(dbPath)
+		insertSampleData(store)
+	} else {
+		store = initializeAndOpenGraph(*db)
+	}
+	defer store.Close()
+	ctx := context.TODO()
+
+	var clinics []Clinic
+	checkErr(schema.LoadTo(ctx, store, &clinics))
+	sort.Slice(clinics, func(i, j int) bool { return clinics[i].Name < clinics[j].Name })
+
+	schedules := make([]Schedule, len(clinics))
+	for i, c := range clinics {
+		schedules[i] = NewSchedule(c)
+	}
+
+	if *openAt != "" {
+		t := time.Now()
+		if *openAt != "now" {
+			var err error
+			t, err = time.Parse("2006-01-02T15:04Z07:00", *openAt)
+			checkErr(err)
+		}
+		for _, s := range schedules {
+			printOpen(s, t)
+		}
+		return
+	}
+
+	if *ics != "" || *icsAdmin != "" {
+		name, selected := selectClinics(ctx, store, schedules, *ics, *icsAdmin)
+		from := time.Now()
+		if *weekOf != "" {
+			var err error
+			from, err = time.Parse(DateLayout, *weekOf)
+			checkErr(err)
+		}
+		w, closeOut := output(*out)
+		defer closeOut()
+		checkErr(WriteICS(w, name, selected, from))
+		return
+	}
+
+	if *weekOf != "" {
+		date, err := time.Parse(DateLayout, *weekOf)
+		checkErr(err)
+		for i := range schedules {
+			schedules[i] = schedules[i].WeekOf(date)
+		}
+	}
+
+	w, closeOut := output(*out)
+	defer closeOut()
+	checkErr(WriteReport(w, *format, schedules))
+}
+
+// output opens the file to write to, stdout for -.
+func output(path string) (io.Writer, func()) {
+	if path == "-" {
+		return os.Stdout, func() {}
+	}
+	f, err := os.Create(path)
+	checkErr(err)
+	return f, func() { checkErr(f.Close()) }
+}
+
+// selectClinics returns the schedules of a calendar and its name: the clinic
+// with a name, or the clinics created by the admin with an email.
+func selectClinics(ctx context.Context, store *cayley.Handle, schedules []Schedule, clinic, adminEmail string) (string, []Schedule) {
+	if clinic != "" {
+		for _, s := range schedules {
+			if s.Clinic == clinic {
+				return s.Clinic, []Schedule{s}
+			}
+		}
+		log.Fatalf("no clinic named %q", clinic)
+	}
+
+	adminID, err := findAdminID(store, adminEmail)
+	checkErr(err)
+	ids, err := cayley.StartPath(store, adminID).In(quad.IRI("createdBy")).Iterate(ctx).AllValues(nil)
+	checkErr(err)
+	created := make(map[quad.IRI]bool)
+	for _, id := range ids {
+		if iri, ok := id.(quad.IRI); ok {
+			created[iri] = true
+		}
+	}
+
+	var selected []Schedule
+	for _, s := range schedules {
+		if created[s.ID] {
+			selected = append(selected, s)
+		}
+	}
+	return "Clinics of " + adminEmail, selected
+}
+
+// printOpen tells if a clinic is open at a time, or when it opens next, in
+// its local time.
+func printOpen(s Schedule, t time.Time) {
+	if s.OpenAt(t) {
+		fmt.Printf("%s: open\n", s.Clinic)
+		return
+	}
+	next, ok := s.NextOpening(t)
+	if !ok {
+		fmt.Printf("%s: closed\n", s.Clinic)
+		return
+	}
+	fmt.Printf("%s: closed, opens %s\n", s.Clinic, next.Format("Monday 2 Jan 15:04 MST"))
+}
+
+func checkErr(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func initializeAndOpenGraph(dbFile string) *cayley.Handle {
+	graph.InitQuadStore("bolt", dbFile, nil)
+
+	// Open and use the database
+	store, err := cayley.NewGraph("bolt", dbFile, nil)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return store
+}
+
+// insertSampleData creates an admin and clinics with opening hours, some of
+// them with mistakes for the report to find.
+func insertSampleData(store *cayley.Handle) {
+	a := Admin{
+		Name:           "Josh",
+		Email:          "josh_f@gmail.com",
+		HashedPassword: "435iue8uou9eu",
+	}
+	_, err := insert(store, a)
+	checkErr(err)
+
+	adminId, err := findAdminID(store, a.Email)
+	checkErr(err)
+
+	var healthy []OpeningHours
+	for _, d := range []quad.IRI{"schema:Monday", "schema:Tuesday", "schema:Wednesday", "schema:Thursday", "schema:Friday"} {
+		healthy = append(healthy,
+			OpeningHours{DayOfWeek: d, Slot: 1, Opens: "08:00", Closes: "12:00"},
+			OpeningHours{DayOfWeek: d, Slot: 2, Opens: "13:00", Closes: "18:00"},
+		)
+	}
+	healthy = append(healthy, OpeningHours{DayOfWeek: "schema:Saturday", Slot: 1, Opens: "09:00", Closes: "12:30"})
+
+	clinics := []Clinic{
+		{
+			Name:      "Healthy Life",
+			Address1:  "11 boar st, Singapore 11233",
+			OfficeTel: "65 6100 0940",
+			Hours:     healthy,
+		},
+		{
+			Name:      "Heal Now",
+			Address1:  "3234 Rot Road, Singapore",
+			OfficeTel: "65 6100 0939",
+			Hours: []OpeningHours{
+				// two slots that overlap, merged into 08:00–14:00
+				{DayOfWeek: "schema:Monday", Slot: 1, Opens: "08:00", Closes: "12:00"},
+				{DayOfWeek: "schema:Monday", Slot: 2, Opens: "11:00", Closes: "14:00"},
+				// no slot 2
+				{DayOfWeek: "schema:Wednesday", Slot: 1, Opens: "08:00", Closes: "12:00"},
+				{DayOfWeek: "schema:Wednesday", Slot: 3, Opens: "16:00", Closes: "20:00"},
+				// closes before it opens
+				{DayOfWeek: "schema:Friday", Slot: 1, Opens: "18:00", Closes: "09:00"},
+			},
+		},
+		{
+			Name:      "Harley Street Clinic",
+			Address1:  "35 Weymouth St, London W1G 8BJ",
+			OfficeTel: "44 20 7935 7700",
+			TimeZone:  "Europe/London", // clocks change on the last Sundays of March and October
+			Hours: []OpeningHours{
+				{DayOfWeek: "schema:Saturday", Slot: 1, Opens: "09:00", Closes: "13:00"},
+				{DayOfWeek: "schema:Sunday", Slot: 1, Opens: "00:00", Closes: "04:00"}, // night service, across the clock change
+			},
+		},
+	}
+	// special hours can come with the clinic
+	clinics = append(clinics, *loadJSON("clinic.json"))
+
+	for _, c := range clinics {
+		// an ID field is written as it is, so every clinic without one would be <>
+		c.ID = quad.IRI(uuid.NewV1().String())
+		c.CreatedBy = adminId
+		_, err := insert(store, c)
+		checkErr(err)
+	}
+}
+
+func loadJSON(JSONFile string) *Clinic {
+	raw, err := ioutil.ReadFile(JSONFile)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	var c Clinic
+
+	err = json.Unmarshal(raw, &c)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	return &c
+}
+
+func insert(h *cayley.Handle, o interface{}) (quad.Value, error) {
+	qw := graph.NewWriter(h)
+	id, err := schema.WriteAsQuads(qw, o)
+	// don't forget to close a writer; it has some internal buffering, and
+	// writing what is left of it can fail too
+	if cerr := qw.Close(); err == nil {
+		err = cerr
+	}
+	return id, err
+}
+
+func findAdminID(store *cayley.Handle, email string) (quad.IRI, error) {
+	p := cayley.StartPath(store).Has(quad.IRI("email"), quad.String(email))
+	id, err := p.Iterate(nil).FirstValue(nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	return id.(quad.IRI), nil
+}
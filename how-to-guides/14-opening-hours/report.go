@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Formats lists the names accepted by WriteReport.
+var Formats = []string{"text", "markdown", "html"}
+
+// WriteReport writes the schedules of clinics in one of Formats.
+func WriteReport(w io.Writer, format string, schedules []Schedule) error {
+	switch format {
+	case "text", "":
+		return WriteText(w, schedules)
+	case "markdown", "md":
+		return WriteMarkdown(w, schedules)
+	case "html":
+		return WriteHTML(w, schedules)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// openFor returns how many minutes of an hour of the day are open.
+func (d Day) openFor(hour int) int {
+	from, to := Clock(hour*60), Clock(hour*60+60)
+	total := 0
+	for _, i := range d.Open {
+		if i.Opens < to && i.Closes > from {
+			total += int(minClock(i.Closes, to) - maxClock(i.Opens, from))
+		}
+	}
+	return total
+}
+
+func minClock(a, b Clock) Clock {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxClock(a, b Clock) Clock {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// bar draws a day with one character per hour: # open the whole hour, +
+// open part of it, . closed.
+func (d Day) bar() string {
+	var b strings.Builder
+	for h := 0; h < 24; h++ {
+		switch d.openFor(h) {
+		case 60:
+			b.WriteByte('#')
+		case 0:
+			b.WriteByte('.')
+		default:
+			b.WriteByte('+')
+		}
+	}
+	return b.String()
+}
+
+// Label is the name of a day in reports, ex: Monday, or Monday 25 Dec for
+// the days of a given week.
+func (d Day) Label() string {
+	if d.Date.IsZero() {
+		return d.Weekday.String()
+	}
+	return d.Date.Format("Monday 2 Jan")
+}
+
+// Text lists the intervals of a day, ex: 08:00–12:00, 13:00–18:00, and
+// says when they are special hours, ex: closed (Christmas).
+func (d Day) Text() string {
+	var text string
+	if d.Closed() {
+		text = "closed"
+	} else {
+		s := make([]string, len(d.Open))
+		for i, o := range d.Open {
+			s[i] = o.String()
+		}
+		text = strings.Join(s, ", ")
+	}
+
+	if d.Exception != nil {
+		note := d.Exception.Description
+		if note == "" {
+			note = "special hours"
+		}
+		text += " (" + note + ")"
+	}
+	if d.ClocksChange() {
+		text += " (clocks change)"
+	}
+	return text
+}
+
+// formatHours writes a duration in hours, ex: 9h or 7h30.
+func formatHours(d time.Duration) string {
+	h, m := int(d/time.Hour), int(d%time.Hour/time.Minute)
+	if m == 0 {
+		return fmt.Sprintf("%dh", h)
+	}
+	return fmt.Sprintf("%dh%02d", h, m)
+}
+
+// title is the clinic and its time zone, ex: Heal Now (Asia/Singapore).
+func (s Schedule) title() string {
+	if s.Location == nil {
+		return s.Clinic
+	}
+	return s.Clinic + " (" + s.Location.String() + ")"
+}
+
+// Summary is the last line of a schedule, ex: "Open 45h a week, closed on
+// Saturday and Sunday."
+func (s Schedule) Summary() string {
+	line := "Open " + formatHours(s.WeeklyHours()) + " a week"
+	var names []string
+	for _, d := range s.Days {
+		if d.Closed() {
+			names = append(names, d.Label())
+		}
+	}
+	switch {
+	case len(names) == len(s.Days):
+		return "Closed all week."
+	case len(names) > 0:
+		if len(names) == 1 {
+			line += ", closed on " + names[0]
+		} else {
+			line += ", closed on " + strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+		}
+	}
+	return line + "."
+}
+
+// WriteText writes the schedules as plain text, with a bar per day.
+func WriteText(w io.Writer, schedules []Schedule) error {
+	for i, s := range schedules {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, s.title())
+		fmt.Fprintln(w, strings.Repeat("-", len([]rune(s.title()))))
+
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "\tOpen\t00    06    12    18\tHours")
+		for _, d := range s.Days {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", d.Label(), d.Text(), d.bar(), formatHours(d.Hours()))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		fmt.Fprintln(w, s.Summary())
+		for _, e := range s.Exceptions {
+			fmt.Fprintln(w, "special:", e)
+		}
+		for _, warning := range s.Warnings {
+			fmt.Fprintln(w, "warning:", warning)
+		}
+	}
+	return nil
+}
+
+// WriteMarkdown writes the schedules as Markdown, one table per clinic.
+func WriteMarkdown(w io.Writer, schedules []Schedule) error {
+	for i, s := range schedules {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "### %s\n\n", s.title())
+		fmt.Fprintln(w, "| Day | Open | Hours |")
+		fmt.Fprintln(w, "| --- | --- | ---: |")
+		for _, d := range s.Days {
+			fmt.Fprintf(w, "| %s | %s | %s |\n", d.Label(), d.Text(), formatHours(d.Hours()))
+		}
+		fmt.Fprintf(w, "\n%s\n", s.Summary())
+
+		if len(s.Exceptions) > 0 {
+			fmt.Fprintln(w, "\nSpecial hours:")
+			for _, e := range s.Exceptions {
+				fmt.Fprintf(w, "* %s\n", e)
+			}
+		}
+
+		if len(s.Warnings) > 0 {
+			fmt.Fprintln(w)
+			for _, warning := range s.Warnings {
+				fmt.Fprintf(w, "> **Warning:** %s  \n", warning)
+			}
+		}
+	}
+	return nil
+}
+
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"hours": formatHours,
+	"cells": func(d Day) []int {
+		cells := make([]int, 24)
+		for h := range cells {
+			cells[h] = d.openFor(h)
+		}
+		return cells
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Opening hours</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 0.5em; }
+th, td { padding: 2px 6px; text-align: left; }
+td.h { width: 12px; padding: 0; border-left: 1px solid #fff; }
+td.open { background: #4caf50; }
+td.part { background: #a5d6a7; }
+td.closed { background: #eee; }
+.warning { color: #b71c1c; }
+</style>
+</head>
+<body>
+{{range .}}
+<h2>{{.Clinic}} <small>{{.Location}}</small></h2>
+<table>
+<tr><th>Day</th><th colspan="24">00 – 24</th><th>Open</th><th>Hours</th></tr>
+{{range .Days}}<tr>
+<th>{{.Label}}</th>
+{{range $h, $m := cells .}}<td class="h {{if eq $m 60}}open{{else if eq $m 0}}closed{{else}}part{{end}}" title="{{$h}}:00, {{$m}} min open"></td>{{end}}
+<td>{{.Text}}</td><td>{{hours .Hours}}</td>
+</tr>
+{{end}}</table>
+<p>{{.Summary}}</p>
+{{with .Exceptions}}<p>Special hours:</p>
+<ul>
+{{range .}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}{{range .Warnings}}<p class="warning">Warning: {{.}}</p>
+{{end}}{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes the schedules as an HTML page, with a colored grid of
+// hours per clinic.
+func WriteHTML(w io.Writer, schedules []Schedule) error {
+	return htmlReport.Execute(w, schedules)
+}
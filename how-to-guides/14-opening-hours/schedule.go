@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cayleygraph/cayley/quad"
+)
+
+// week is the order of the days in reports.
+var week = []time.Weekday{
+	time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday,
+}
+
+// days maps Go weekdays to the schema.org days used in DayOfWeek.
+var days = map[time.Weekday]quad.IRI{
+	time.Monday:    "schema:Monday",
+	time.Tuesday:   "schema:Tuesday",
+	time.Wednesday: "schema:Wednesday",
+	time.Thursday:  "schema:Thursday",
+	time.Friday:    "schema:Friday",
+	time.Saturday:  "schema:Saturday",
+	time.Sunday:    "schema:Sunday",
+}
+
+// weekdayOf is the reverse of days. It also accepts full schema.org IRIs,
+// ex: http://schema.org/Monday, and the short names of clinic JSON files,
+// ex: mon.
+func weekdayOf(day quad.IRI) (time.Weekday, bool) {
+	name := strings.ToLower(string(day))
+	if i := strings.LastIndexAny(name, ":/"); i >= 0 {
+		name = name[i+1:]
+	}
+	for d := range days {
+		full := strings.ToLower(d.String())
+		if name == full || name == full[:3] {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// Clock is a time of the day, in minutes after midnight.
+type Clock int
+
+// ParseClock parses the time of opening hours, ex: 12:00 or 12:00:00.
+// 24:00 is the end of the day.
+func ParseClock(s string) (Clock, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	var n [3]int
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil || len(p) != 2 {
+			return 0, fmt.Errorf("invalid time %q", s)
+		}
+		n[i] = v
+	}
+	h, m, sec := n[0], n[1], n[2]
+	if m > 59 || sec > 59 || h > 24 || (h == 24 && (m > 0 || sec > 0)) {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return Clock(h*60 + m), nil
+}
+
+func (c Clock) String() string {
+	return fmt.Sprintf("%02d:%02d", int(c)/60, int(c)%60)
+}
+
+// Interval is a time the clinic is open, from Opens up to, but not
+// including, Closes.
+type Interval struct {
+	Opens, Closes Clock
+}
+
+func (i Interval) String() string {
+	return i.Opens.String() + "–" + i.Closes.String()
+}
+
+// Duration returns how long the interval is.
+func (i Interval) Duration() time.Duration {
+	return time.Duration(i.Closes-i.Opens) * time.Minute
+}
+
+// Day is the schedule of one day of the week.
+type Day struct {
+	Weekday time.Weekday
+	Open    []Interval // sorted, merged: slots that overlap or touch are one interval
+
+	// Date, Location and Exception are only set for the days of a given
+	// week, see Schedule.WeekOf. Exception is the special hours of that
+	// date, if any.
+	Date      time.Time
+	Location  *time.Location
+	Exception *Exception
+}
+
+// Closed tells if the clinic does not open on that day.
+func (d Day) Closed() bool {
+	return len(d.Open) == 0
+}
+
+// Hours returns how long the clinic is open on that day. On a date when
+// the clocks change, it is the time that really goes by, ex: 08:00–12:00 is
+// 3 hours if the clocks go forward at 10:00.
+func (d Day) Hours() time.Duration {
+	var total time.Duration
+	for _, i := range d.Open {
+		if d.Date.IsZero() || d.Location == nil {
+			total += i.Duration()
+		} else {
+			total += clockOn(d.Date, i.Closes, d.Location).Sub(clockOn(d.Date, i.Opens, d.Location))
+		}
+	}
+	return total
+}
+
+// ClocksChange tells if the clocks go forward or back on that day. It is
+// only known for the days of a given week.
+func (d Day) ClocksChange() bool {
+	return !d.Date.IsZero() && d.Location != nil && clocksChange(d.Date, d.Location)
+}
+
+// Schedule is the week of a clinic, Monday first. Times are in the local
+// time of the clinic.
+type Schedule struct {
+	ID       quad.IRI // of the clinic
+	Clinic   string
+	Address  string
+	Location *time.Location
+	Days     []Day
+	Warnings []string // problems found in the opening hours, ex: overlapping slots
+
+	// Exceptions are the special hours of the clinic, ex: public holidays.
+	// They replace the regular hours of the dates they are valid for.
+	Exceptions []Exception
+}
+
+// WeeklyHours returns how long the clinic is open in a week.
+func (s Schedule) WeeklyHours() time.Duration {
+	var total time.Duration
+	for _, d := range s.Days {
+		total += d.Hours()
+	}
+	return total
+}
+
+// ClosedDays returns the days the clinic does not open.
+func (s Schedule) ClosedDays() []time.Weekday {
+	var closed []time.Weekday
+	for _, d := range s.Days {
+		if d.Closed() {
+			closed = append(closed, d.Weekday)
+		}
+	}
+	return closed
+}
+
+// slot is an opening hours entry, once its times are parsed.
+type slot struct {
+	num int
+	Interval
+}
+
+// parseSlot reads the times of an opening hours entry.
+func parseSlot(h OpeningHours) (slot, error) {
+	opens, err := ParseClock(h.Opens)
+	if err != nil {
+		return slot{}, err
+	}
+	closes, err := ParseClock(h.Closes)
+	if err != nil {
+		return slot{}, err
+	}
+	if closes <= opens {
+		return slot{}, fmt.Errorf("closes at %s, before it opens at %s", closes, opens)
+	}
+	return slot{num: h.Slot, Interval: Interval{Opens: opens, Closes: closes}}, nil
+}
+
+// mergeSlots sorts the slots of a day and merges the ones that overlap or
+// touch. It warns about overlaps and gaps in the slot numbers; label is the
+// day in warnings, ex: Monday.
+func mergeSlots(label string, slots []slot, warn func(format string, args ...interface{})) []Interval {
+	sort.Slice(slots, func(i, j int) bool {
+		if slots[i].Opens != slots[j].Opens {
+			return slots[i].Opens < slots[j].Opens
+		}
+		return slots[i].num < slots[j].num
+	})
+
+	// the slots of a day are numbered 1, 2, 3...
+	nums := make([]int, len(slots))
+	for i, sl := range slots {
+		nums[i] = sl.num
+	}
+	sort.Ints(nums)
+	prev := 0
+	for _, n := range nums {
+		switch {
+		case n == prev:
+			warn("%s: more than one slot %d", label, n)
+		case n > prev+1:
+			warn("%s: no slot %d", label, prev+1)
+		}
+		prev = n
+	}
+
+	var open []Interval
+	var widest slot // the slot the last interval closes with
+	for _, sl := range slots {
+		n := len(open)
+		if n > 0 && sl.Opens < open[n-1].Closes {
+			warn("%s: slot %d (%s) overlaps slot %d (%s)", label, sl.num, sl.Interval, widest.num, widest.Interval)
+		}
+		if n > 0 && sl.Opens <= open[n-1].Closes {
+			if sl.Closes > open[n-1].Closes {
+				open[n-1].Closes = sl.Closes
+				widest = sl
+			}
+			continue
+		}
+		open = append(open, sl.Interval)
+		widest = sl
+	}
+	return open
+}
+
+// NewSchedule builds the week of a clinic from its opening hours, and
+// reads its special hours. Slots that overlap or touch are merged. It warns
+// about:
+//   - slots that can't be read: unknown day, invalid time, closing before
+//     opening;
+//   - slots that overlap;
+//   - gaps in the slot numbers of a day, ex: slots 1 and 3 without a 2,
+//     which usually means a slot was lost, and slots with the same number.
+func NewSchedule(c Clinic) Schedule {
+	s := Schedule{ID: c.ID, Clinic: c.Name, Address: c.Address1}
+	warn := func(format string, args ...interface{}) {
+		s.Warnings = append(s.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	loc, err := locationOf(c)
+	if err != nil {
+		warn("unknown time zone %q, using %s", c.TimeZone, DefaultTimeZone)
+		loc, _ = time.LoadLocation(DefaultTimeZone)
+	}
+	s.Location = loc
+
+	byDay := make(map[time.Weekday][]slot)
+	for _, h := range c.Hours {
+		d, ok := weekdayOf(h.DayOfWeek)
+		if !ok {
+			warn("slot %d: unknown day %q", h.Slot, h.DayOfWeek)
+			continue
+		}
+		sl, err := parseSlot(h)
+		if err != nil {
+			warn("%s slot %d: %v", d, h.Slot, err)
+			continue
+		}
+		byDay[d] = append(byDay[d], sl)
+	}
+
+	for _, wd := range week {
+		s.Days = append(s.Days, Day{
+			Weekday: wd,
+			Open:    mergeSlots(wd.String(), byDay[wd], warn),
+		})
+	}
+
+	s.Exceptions = exceptionsOf(c.Special, warn)
+	return s
+}
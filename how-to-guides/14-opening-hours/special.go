@@ -0,0 +1,207 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/cayleygraph/cayley/quad"
+)
+
+// DateLayout is the format of ValidFrom and ValidThrough, ex: 2017-12-25.
+const DateLayout = "2006-01-02"
+
+// Exception is a change of the regular opening hours between two dates,
+// ex: a public holiday, a closure for renovation or longer hours before
+// Christmas.
+type Exception struct {
+	From, Through time.Time     // dates, both included
+	Weekday       *time.Weekday // only on this day of the week, every day when nil
+	Description   string
+	Open          []Interval // closed when empty
+}
+
+// Closed tells if the clinic is closed during the exception.
+func (e Exception) Closed() bool {
+	return len(e.Open) == 0
+}
+
+// covers tells if the exception is valid on a date.
+func (e Exception) covers(date time.Time) bool {
+	d := dateOf(date)
+	if d.Before(e.From) || d.After(e.Through) {
+		return false
+	}
+	return e.Weekday == nil || *e.Weekday == d.Weekday()
+}
+
+// label is when the exception is valid, ex: "2017-12-25" or
+// "2017-12-01 – 2017-12-23, Saturday".
+func (e Exception) label() string {
+	s := e.From.Format(DateLayout)
+	if !e.Through.Equal(e.From) {
+		s += " – " + e.Through.Format(DateLayout)
+	}
+	if e.Weekday != nil {
+		s += ", " + e.Weekday.String()
+	}
+	return s
+}
+
+func (e Exception) String() string {
+	s := e.label() + ": "
+	if e.Closed() {
+		s += "closed"
+	} else {
+		s += Day{Open: e.Open}.Text()
+	}
+	if e.Description != "" {
+		s += " (" + e.Description + ")"
+	}
+	return s
+}
+
+// dateOf drops the time of the day, so dates can be compared.
+func dateOf(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// isClosure tells if special hours close the clinic: schema.org writes it as
+// opening and closing at the same time, usually 00:00.
+func isClosure(h OpeningHours) bool {
+	opens, err := ParseClock(h.Opens)
+	if err != nil {
+		return false
+	}
+	closes, err := ParseClock(h.Closes)
+	return err == nil && opens == closes
+}
+
+// exceptionsOf groups special hours into exceptions: entries with the same
+// dates, day and description are the slots of one exception.
+func exceptionsOf(special []OpeningHours, warn func(format string, args ...interface{})) []Exception {
+	type key struct {
+		from, through string
+		day           quad.IRI
+		description   string
+	}
+	var keys []key
+	groups := make(map[key][]OpeningHours)
+	for _, h := range special {
+		k := key{from: h.ValidFrom, through: h.ValidThrough, day: h.DayOfWeek, description: h.Description}
+		if k.through == "" {
+			k.through = k.from // a single day
+		}
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], h)
+	}
+
+	var exceptions []Exception
+	for _, k := range keys {
+		from, err := time.Parse(DateLayout, k.from)
+		if err != nil {
+			warn("special hours %q: invalid validFrom %q", k.description, k.from)
+			continue
+		}
+		through, err := time.Parse(DateLayout, k.through)
+		if err != nil {
+			warn("special hours %q: invalid validThrough %q", k.description, k.through)
+			continue
+		}
+		e := Exception{From: from, Through: through, Description: k.description}
+		if through.Before(from) {
+			warn("special hours %s: valid through %s, before it starts", e.label(), k.through)
+			continue
+		}
+		if k.day != "" {
+			wd, ok := weekdayOf(k.day)
+			if !ok {
+				warn("special hours %s: unknown day %q", e.label(), k.day)
+				continue
+			}
+			e.Weekday = &wd
+		}
+
+		var slots []slot
+		closed := false
+		for _, h := range groups[k] {
+			if isClosure(h) {
+				closed = true
+				continue
+			}
+			sl, err := parseSlot(h)
+			if err != nil {
+				warn("special hours %s slot %d: %v", e.label(), h.Slot, err)
+				continue
+			}
+			slots = append(slots, sl)
+		}
+		if closed && len(slots) > 0 {
+			warn("special hours %s: both closed and open, using the open slots", e.label())
+		}
+		e.Open = mergeSlots("special hours "+e.label(), slots, warn)
+		exceptions = append(exceptions, e)
+	}
+
+	sort.SliceStable(exceptions, func(i, j int) bool {
+		return exceptions[i].From.Before(exceptions[j].From)
+	})
+	return exceptions
+}
+
+// exceptionOn returns the special hours valid on a date, or nil. When more
+// than one is valid, the shortest wins, ex: a public holiday during a month
+// of longer hours.
+func (s Schedule) exceptionOn(date time.Time) *Exception {
+	var found *Exception
+	for i := range s.Exceptions {
+		e := &s.Exceptions[i]
+		if !e.covers(date) {
+			continue
+		}
+		if found == nil || e.Through.Sub(e.From) < found.Through.Sub(found.From) {
+			found = e
+		}
+	}
+	return found
+}
+
+// On returns the hours of a date in the local time of the clinic: its
+// special hours if it has some, or the regular hours of its day of the
+// week.
+func (s Schedule) On(date time.Time) Day {
+	day := Day{Weekday: date.Weekday(), Date: dateOf(date), Location: s.Location}
+	if e := s.exceptionOn(date); e != nil {
+		day.Open = e.Open
+		day.Exception = e
+		return day
+	}
+	for _, d := range s.Days {
+		if d.Weekday == day.Weekday {
+			day.Open = d.Open
+		}
+	}
+	return day
+}
+
+// WeekOf returns the schedule of the week, Monday to Sunday, that has the
+// date in it, with the special hours of those dates instead of the regular
+// hours. Only the exceptions of that week are kept.
+func (s Schedule) WeekOf(date time.Time) Schedule {
+	offset := (int(date.Weekday()) + 6) % 7 // days since Monday
+	monday := dateOf(date).AddDate(0, 0, -offset)
+
+	w := Schedule{ID: s.ID, Clinic: s.Clinic, Address: s.Address, Location: s.Location, Warnings: s.Warnings}
+	used := make(map[*Exception]bool)
+	for i := range week {
+		d := s.On(monday.AddDate(0, 0, i))
+		w.Days = append(w.Days, d)
+		if d.Exception != nil && !used[d.Exception] {
+			used[d.Exception] = true
+			w.Exceptions = append(w.Exceptions, *d.Exception)
+		}
+	}
+	return w
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"time"
+	_ "time/tzdata" // so time zones work on machines without a tz database, ex: Windows or scratch containers
+)
+
+// DefaultTimeZone is the time zone of clinics that don't have one, ex: the
+// ones added before this guide.
+const DefaultTimeZone = "Asia/Singapore"
+
+// locationOf loads the time zone of a clinic, an IANA name like
+// Asia/Singapore or Europe/London.
+func locationOf(c Clinic) (*time.Location, error) {
+	name := c.TimeZone
+	if name == "" {
+		name = DefaultTimeZone
+	}
+	return time.LoadLocation(name)
+}
+
+// clockOn returns the time a clock shows a time of the day on a date, in a
+// time zone. On the day clocks go forward, a time that doesn't exist, ex:
+// 02:30, is moved after the change.
+func clockOn(date time.Time, c Clock, loc *time.Location) time.Time {
+	y, m, d := date.Date()
+	return time.Date(y, m, d, int(c)/60, int(c)%60, 0, 0, loc)
+}
+
+// clocksChange tells if the clocks of a time zone go forward or back on a
+// date.
+func clocksChange(date time.Time, loc *time.Location) bool {
+	_, start := clockOn(date, 0, loc).Zone()
+	_, end := clockOn(date, 24*60, loc).Zone()
+	return start != end
+}
+
+// OpenAt tells if the clinic is open at a time, special hours included. The
+// time can be in any time zone; it is compared with the opening hours in the
+// local time of the clinic.
+func (s Schedule) OpenAt(t time.Time) bool {
+	local := t.In(s.Location)
+	now := Clock(local.Hour()*60 + local.Minute())
+	for _, i := range s.On(local).Open {
+		if i.Opens <= now && now < i.Closes {
+			return true
+		}
+	}
+	return false
+}
+
+// maxSearchDays is how far NextOpening looks ahead, long enough to get over
+// any closure.
+const maxSearchDays = 366
+
+// NextOpening returns the next time the clinic opens after t, in its local
+// time, or false if it does not open in the next year. If the clinic is open
+// at t, it is when it opens again after closing, see OpenAt.
+func (s Schedule) NextOpening(t time.Time) (time.Time, bool) {
+	today := dateOf(t.In(s.Location))
+	for i := 0; i <= maxSearchDays; i++ {
+		date := today.AddDate(0, 0, i)
+		for _, iv := range s.On(date).Open {
+			if opens := clockOn(date, iv.Opens, s.Location); opens.After(t) {
+				return opens, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+)
+
+// DefaultTimeZone is the time zone of clinics that don't have one.
+const DefaultTimeZone = "Asia/Singapore"
+
+// days maps the schema.org days used in DayOfWeek to Go weekdays.
+var days = map[quad.IRI]time.Weekday{
+	"schema:Monday":    time.Monday,
+	"schema:Tuesday":   time.Tuesday,
+	"schema:Wednesday": time.Wednesday,
+	"schema:Thursday":  time.Thursday,
+	"schema:Friday":    time.Friday,
+	"schema:Saturday":  time.Saturday,
+	"schema:Sunday":    time.Sunday,
+}
+
+// Interval is a time range, the start included and the end excluded, ex: an
+// opening slot or an appointment.
+type Interval struct {
+	Start, End time.Time
+}
+
+func (i Interval) overlaps(o Interval) bool {
+	return i.Start.Before(o.End) && o.Start.Before(i.End)
+}
+
+// within tells if the interval is inside another one.
+func (i Interval) within(o Interval) bool {
+	return !i.Start.Before(o.Start) && !i.End.After(o.End)
+}
+
+func (i Interval) String() string {
+	return i.Start.Format("Mon 2 Jan 15:04") + "–" + i.End.Format("15:04")
+}
+
+// parseClock parses a time of the day, ex: 08:00 or 08:00:00, and returns
+// the minutes since midnight.
+func parseClock(s string) (int, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	h, err := strconv.Atoi(parts[0])
+	if err != nil || h < 0 || h > 24 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	m, err := strconv.Atoi(parts[1])
+	if err != nil || m < 0 || m > 59 || h*60+m > 24*60 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return h*60 + m, nil
+}
+
+// locationOf loads the time zone of a clinic.
+func locationOf(c Clinic) (*time.Location, error) {
+	if c.TimeZone == "" {
+		return time.LoadLocation(DefaultTimeZone)
+	}
+	return time.LoadLocation(c.TimeZone)
+}
+
+// OpenOn returns the opening slots of a clinic on a date, in its local time
+// and in order. Slots with invalid times are skipped.
+func OpenOn(c Clinic, date time.Time) ([]Interval, error) {
+	loc, err := locationOf(c)
+	if err != nil {
+		return nil, err
+	}
+	y, m, d := date.In(loc).Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, loc)
+
+	var open []Interval
+	for _, h := range c.Hours {
+		if wd, ok := days[h.DayOfWeek]; !ok || wd != day.Weekday() {
+			continue
+		}
+		opens, err := parseClock(h.Opens)
+		if err != nil {
+			continue
+		}
+		closes, err := parseClock(h.Closes)
+		if err != nil || closes <= opens {
+			continue
+		}
+		open = append(open, Interval{
+			Start: time.Date(y, m, d, opens/60, opens%60, 0, 0, loc),
+			End:   time.Date(y, m, d, closes/60, closes%60, 0, 0, loc),
+		})
+	}
+	sort.Slice(open, func(i, j int) bool { return open[i].Start.Before(open[j].Start) })
+	return open, nil
+}
+
+// Bookable splits the opening slots of a clinic on a date into intervals of
+// a length, ex: 08:00–08:30, 08:30–09:00. What is left at the end of a slot,
+// shorter than the length, can't be booked.
+func Bookable(c Clinic, date time.Time, length time.Duration) ([]Interval, error) {
+	if length <= 0 || length%BookingUnit != 0 {
+		return nil, fmt.Errorf("length %v is not a multiple of %v", length, BookingUnit)
+	}
+	open, err := OpenOn(c, date)
+	if err != nil {
+		return nil, err
+	}
+	var bookable []Interval
+	for _, slot := range open {
+		for start := slot.Start; !start.Add(length).After(slot.End); start = start.Add(length) {
+			bookable = append(bookable, Interval{Start: start, End: start.Add(length)})
+		}
+	}
+	return bookable, nil
+}
+
+// Available returns the intervals of a length a practitioner can still be
+// booked for on a date: the bookable intervals of their clinic that don't
+// overlap an appointment.
+func Available(ctx context.Context, store *cayley.Handle, practitioner quad.IRI, date time.Time, length time.Duration) ([]Interval, error) {
+	p, c, err := loadPractitioner(ctx, store, practitioner)
+	if err != nil {
+		return nil, err
+	}
+	bookable, err := Bookable(c, date, length)
+	if err != nil {
+		return nil, err
+	}
+	booked, err := Appointments(ctx, store, p.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var free []Interval
+	for _, i := range bookable {
+		taken := false
+		for _, a := range booked {
+			if i.overlaps(a.Interval()) {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			free = append(free, i)
+		}
+	}
+	return free, nil
+}
+
+// loadPractitioner loads a practitioner and the clinic they work at.
+func loadPractitioner(ctx context.Context, store *cayley.Handle, id quad.IRI) (Practitioner, Clinic, error) {
+	var p Practitioner
+	if err := schema.LoadTo(ctx, store, &p, id); err != nil {
+		return p, Clinic{}, fmt.Errorf("practitioner %v: %v", id, err)
+	}
+	var c Clinic
+	if err := schema.LoadTo(ctx, store, &c, p.Clinic); err != nil {
+		return p, c, fmt.Errorf("clinic %v of practitioner %v: %v", p.Clinic, id, err)
+	}
+	return p, c, nil
+}
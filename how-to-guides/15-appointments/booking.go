@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	uuid "github.com/satori/go.uuid"
+)
+
+// BookingUnit is the precision of appointments: they start and end on a
+// multiple of it, ex: 09:05 but not 09:07.
+const BookingUnit = 5 * time.Minute
+
+var (
+	ErrBadTime      = errors.New("appointment must end after it starts, on a multiple of 5 minutes")
+	ErrClosed       = errors.New("clinic is not open for the whole appointment")
+	ErrDoubleBooked = errors.New("practitioner already has an appointment at that time")
+	ErrWrongClinic  = errors.New("practitioner does not work at that clinic")
+)
+
+// busyAt links a practitioner to every BookingUnit of their appointments,
+// ex: 09:00, 09:05, ..., 09:25 for 09:00–09:30. The store refuses to add a
+// quad twice, so two appointments that overlap can't both be written.
+var busyAt = quad.IRI("busyAt")
+
+// Interval is the time of the appointment.
+func (a Appointment) Interval() Interval {
+	return Interval{Start: a.Start, End: a.End}
+}
+
+// busyQuads returns the quads that reserve the time of an appointment.
+func busyQuads(a Appointment) []quad.Quad {
+	var quads []quad.Quad
+	for t := a.Start; t.Before(a.End); t = t.Add(BookingUnit) {
+		// the same instant is the same value, whatever the time zone
+		quads = append(quads, quad.Make(a.Practitioner, busyAt, quad.Time(t.UTC()), nil))
+	}
+	return quads
+}
+
+// txWriter adds the quads written by schema.WriteAsQuads to a transaction.
+type txWriter struct {
+	tx *graph.Transaction
+}
+
+func (w txWriter) WriteQuad(q quad.Quad) error {
+	w.tx.AddQuad(q)
+	return nil
+}
+
+func (w txWriter) WriteQuads(buf []quad.Quad) (int, error) {
+	for _, q := range buf {
+		w.tx.AddQuad(q)
+	}
+	return len(buf), nil
+}
+
+// Book adds an appointment with a practitioner at their clinic, and returns
+// its ID. It fails with ErrClosed if the clinic is not open for the whole
+// appointment, and ErrDoubleBooked if the practitioner already has an
+// appointment at that time, even one booked at the same moment by someone
+// else: the appointment and the time it takes are written in one
+// transaction.
+func Book(ctx context.Context, store *cayley.Handle, a Appointment) (quad.IRI, error) {
+	if !a.End.After(a.Start) || !a.Start.Truncate(BookingUnit).Equal(a.Start) || !a.End.Truncate(BookingUnit).Equal(a.End) {
+		return "", ErrBadTime
+	}
+
+	p, c, err := loadPractitioner(ctx, store, a.Practitioner)
+	if err != nil {
+		return "", err
+	}
+	if a.Clinic == "" {
+		a.Clinic = p.Clinic
+	} else if a.Clinic != p.Clinic {
+		return "", ErrWrongClinic
+	}
+
+	open, err := OpenOn(c, a.Start)
+	if err != nil {
+		return "", err
+	}
+	inside := false
+	for _, slot := range open {
+		if a.Interval().within(slot) {
+			inside = true
+			break
+		}
+	}
+	if !inside {
+		return "", ErrClosed
+	}
+
+	// a quick check for a clear error; the transaction is what prevents
+	// double bookings
+	booked, err := Appointments(ctx, store, a.Practitioner)
+	if err != nil {
+		return "", err
+	}
+	for _, b := range booked {
+		if a.Interval().overlaps(b.Interval()) {
+			return "", ErrDoubleBooked
+		}
+	}
+
+	// an ID field is written as it is, so every appointment without one
+	// would be <>
+	if a.ID == "" {
+		a.ID = quad.IRI(uuid.NewV1().String())
+	}
+	tx := cayley.NewTransaction()
+	if _, err := schema.WriteAsQuads(txWriter{tx}, a); err != nil {
+		return "", err
+	}
+	for _, q := range busyQuads(a) {
+		tx.AddQuad(q)
+	}
+	err = store.ApplyTransaction(tx)
+	if graph.IsQuadExist(err) {
+		return "", ErrDoubleBooked
+	} else if err != nil {
+		return "", err
+	}
+	return a.ID, nil
+}
+
+// Cancel removes an appointment and frees the time of the practitioner.
+func Cancel(ctx context.Context, store *cayley.Handle, id quad.IRI) error {
+	var a Appointment
+	if err := schema.LoadTo(ctx, store, &a, id); err != nil {
+		return fmt.Errorf("appointment %v: %v", id, err)
+	}
+
+	tx := cayley.NewTransaction()
+	it := store.QuadIterator(quad.Subject, store.ValueOf(id))
+	defer it.Close()
+	for it.Next(ctx) {
+		tx.RemoveQuad(store.Quad(it.Result()))
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	for _, q := range busyQuads(a) {
+		tx.RemoveQuad(q)
+	}
+	return store.ApplyTransaction(tx)
+}
+
+// Appointments returns the appointments of a practitioner.
+func Appointments(ctx context.Context, store *cayley.Handle, practitioner quad.IRI) ([]Appointment, error) {
+	p := cayley.StartPath(store, practitioner).In(quad.IRI("practitioner"))
+	var appointments []Appointment
+	err := schema.LoadPathTo(ctx, store, &appointments, p)
+	return appointments, err
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"context"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	_ "github.com/cayleygraph/cayley/graph/kv/bolt"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	uuid "github.com/satori/go.uuid"
+)
+
+var dbPath = "db.boltdb"
+
+type Admin struct {
+	Name           string `json:"name" quad:"name"`
+	Email          string `json:"email" quad:"email"`
+	HashedPassword string `json:"hashedPassword"  quad:"hashed_password"`
+}
+
+type Clinic struct {
+	ID        quad.IRI       `json:"id" quad:"@id"`
+	Name      string         `json:"name" quad:"name"`
+	Address1  string         `json:"address" quad:"address"`
+	CreatedBy quad.IRI       `quad:"createdBy"`
+	OfficeTel string         `json:"officeTel" quad:"officeTel"`
+	TimeZone  string         `json:"timeZone" quad:"timeZone,optional"` // IANA name, ex: Asia/Singapore; DefaultTimeZone when empty
+	Hours     []OpeningHours `json:"hours" quad:"schema:openingHoursSpecification"`
+}
+
+type OpeningHours struct {
+	DayOfWeek quad.IRI `json:"day" quad:"schema:dayOfWeek"`
+	Slot      int      `json:"slot" quad:"slot"`
+	Opens     string   `json:"opens" quad:"schema:opens"` // ex: 12:00 or 12:00:00
+	Closes    string   `json:"closes" quad:"schema:closes"`
+}
+
+// Practitioner is a doctor, nurse or therapist patients book appointments
+// with.
+type Practitioner struct {
+	ID     quad.IRI `json:"id" quad:"@id"`
+	Name   string   `json:"name" quad:"name"`
+	Clinic quad.IRI `json:"clinic" quad:"worksAt"`
+}
+
+// Appointment is a patient seeing a practitioner at a clinic, see Book.
+type Appointment struct {
+	ID           quad.IRI  `json:"id" quad:"@id"`
+	Clinic       quad.IRI  `json:"clinic" quad:"clinic"`
+	Practitioner quad.IRI  `json:"practitioner" quad:"practitioner"`
+	Patient      string    `json:"patient" quad:"patient"`
+	Start        time.Time `json:"start" quad:"start"`
+	End          time.Time `json:"end" quad:"end"`
+}
+
+func init() {
+	schema.RegisterType("Admin", Admin{})
+	schema.RegisterType("Clinic", Clinic{})
+	schema.RegisterType("schema:OpeningHoursSpecification", OpeningHours{})
+	schema.RegisterType("Practitioner", Practitioner{})
+	schema.RegisterType("Appointment", Appointment{})
+	schema.GenerateID = func(_ interface{}) quad.Value {
+		return quad.IRI(uuid.NewV1().String())
+	}
+}
+
+func main() {
+	os.RemoveAll(dbPath)
+	store := initializeAndOpenGraph(dbPath)
+	defer store.Close()
+	ctx := context.TODO()
+
+	tan, lim := insertSampleData(store)
+
+	loc, err := time.LoadLocation(DefaultTimeZone)
+	checkErr(err)
+	monday := time.Date(2018, 1, 8, 0, 0, 0, 0, loc)
+	at := func(hour, min int) time.Time {
+		return monday.Add(time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute)
+	}
+
+	free, err := Available(ctx, store, tan, monday, 30*time.Minute)
+	checkErr(err)
+	fmt.Println("Dr Tan is free on Monday:")
+	printIntervals(free)
+
+	id, err := Book(ctx, store, Appointment{Practitioner: tan, Patient: "Mary", Start: at(9, 0), End: at(9, 30)})
+	checkErr(err)
+	fmt.Println("\nMary booked 09:00–09:30:", id)
+
+	for _, a := range []Appointment{
+		{Practitioner: tan, Patient: "John", Start: at(9, 15), End: at(9, 45)},  // overlaps Mary
+		{Practitioner: tan, Patient: "John", Start: at(12, 0), End: at(12, 30)}, // lunch break
+		{Practitioner: tan, Patient: "John", Start: at(9, 7), End: at(9, 37)},
+	} {
+		_, err := Book(ctx, store, a)
+		fmt.Printf("John can't book %v: %v\n", a.Interval(), err)
+	}
+
+	// ten patients try to book Dr Lim at 10:00 at the same time: only one
+	// of them gets the appointment
+	var wg sync.WaitGroup
+	results := make([]error, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, results[i] = Book(ctx, store, Appointment{Practitioner: lim, Patient: fmt.Sprintf("patient %d", i), Start: at(10, 0), End: at(10, 30)})
+		}(i)
+	}
+	wg.Wait()
+	booked := 0
+	for _, err := range results {
+		if err == nil {
+			booked++
+		} else if err != ErrDoubleBooked {
+			checkErr(err)
+		}
+	}
+	fmt.Printf("\n%d of %d patients booked Dr Lim at 10:00\n", booked, len(results))
+
+	checkErr(Cancel(ctx, store, id))
+	free, err = Available(ctx, store, tan, monday, 30*time.Minute)
+	checkErr(err)
+	fmt.Printf("\nMary cancelled, Dr Tan has %d free slots again\n", len(free))
+}
+
+func printIntervals(intervals []Interval) {
+	for _, i := range intervals {
+		fmt.Println(" ", i)
+	}
+}
+
+func checkErr(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func initializeAndOpenGraph(dbFile string) *cayley.Handle {
+	graph.InitQuadStore("bolt", dbFile, nil)
+
+	// Open and use the database
+	store, err := cayley.NewGraph("bolt", dbFile, nil)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return store
+}
+
+// insertSampleData creates an admin, a clinic and two practitioners working
+// there, and returns the IDs of the practitioners.
+func insertSampleData(store *cayley.Handle) (quad.IRI, quad.IRI) {
+	a := Admin{
+		Name:           "Josh",
+		Email:          "josh_f@gmail.com",
+		HashedPassword: "435iue8uou9eu",
+	}
+	_, err := insert(store, a)
+	checkErr(err)
+
+	adminId, err := findAdminID(store, a.Email)
+	checkErr(err)
+
+	var hours []OpeningHours
+	for _, d := range []quad.IRI{"schema:Monday", "schema:Tuesday", "schema:Wednesday", "schema:Thursday", "schema:Friday"} {
+		hours = append(hours,
+			OpeningHours{DayOfWeek: d, Slot: 1, Opens: "08:00", Closes: "12:00"},
+			OpeningHours{DayOfWeek: d, Slot: 2, Opens: "13:00", Closes: "18:00"},
+		)
+	}
+	// ID fields are written as they are, so every clinic and practitioner
+	// without one would be <>
+	c := Clinic{
+		ID:        quad.IRI(uuid.NewV1().String()),
+		Name:      "Healthy Life",
+		Address1:  "11 boar st, Singapore 11233",
+		CreatedBy: adminId,
+		OfficeTel: "65 6100 0939",
+		Hours:     hours,
+	}
+	_, err = insert(store, c)
+	checkErr(err)
+
+	tan := Practitioner{ID: quad.IRI(uuid.NewV1().String()), Name: "Dr Tan", Clinic: c.ID}
+	_, err = insert(store, tan)
+	checkErr(err)
+	lim := Practitioner{ID: quad.IRI(uuid.NewV1().String()), Name: "Dr Lim", Clinic: c.ID}
+	_, err = insert(store, lim)
+	checkErr(err)
+	return tan.ID, lim.ID
+}
+
+func insert(h *cayley.Handle, o interface{}) (quad.Value, error) {
+	qw := graph.NewWriter(h)
+	id, err := schema.WriteAsQuads(qw, o)
+	// don't forget to close a writer; it has some internal buffering, and
+	// writing what is left of it can fail too
+	if cerr := qw.Close(); err == nil {
+		err = cerr
+	}
+	return id, err
+}
+
+func findAdminID(store *cayley.Handle, email string) (quad.IRI, error) {
+	p := cayley.StartPath(store).Has(quad.IRI("email"), quad.String(email))
+	id, err := p.Iterate(nil).FirstValue(nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	return id.(quad.IRI), nil
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"context"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	_ "github.com/cayleygraph/cayley/graph/kv/bolt"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	uuid "github.com/satori/go.uuid"
+)
+
+var dbPath = "db.boltdb"
+
+type Admin struct {
+	Name           string `json:"name" quad:"name"`
+	Email          string `json:"email" quad:"email"`
+	HashedPassword string `json:"hashedPassword"  quad:"hashed_password"`
+}
+
+type Clinic struct {
+	ID        quad.IRI       `json:"id" quad:"@id"`
+	Name      string         `json:"name" quad:"name"`
+	Address1  string         `json:"address" quad:"address"`
+	CreatedBy quad.IRI       `quad:"createdBy"`
+	OfficeTel string         `json:"officeTel" quad:"officeTel"`
+	Hours     []OpeningHours `json:"hours" quad:"schema:openingHoursSpecification"`
+}
+
+type OpeningHours struct {
+	DayOfWeek quad.IRI `json:"day" quad:"schema:dayOfWeek"`
+	Slot      int      `json:"slot" quad:"slot"`
+	Opens     string   `json:"opens" quad:"schema:opens"` // ex: 12:00 or 12:00:00
+	Closes    string   `json:"closes" quad:"schema:closes"`
+}
+
+// Practitioner is a doctor, nurse or therapist. They can work at several
+// clinics, with different hours at each.
+type Practitioner struct {
+	ID                 quad.IRI      `json:"id" quad:"@id"`
+	Name               string        `json:"name" quad:"name"`
+	RegistrationNumber string        `json:"registrationNumber" quad:"registrationNumber"`        // ex: M12345A, from the Singapore Medical Council
+	Specialties        []quad.IRI    `json:"specialties" quad:"schema:medicalSpecialty,optional"` // ex: schema:Cardiovascular
+	Affiliations       []Affiliation `json:"affiliations" quad:"affiliation,optional"`            // the clinics they work at
+}
+
+// Affiliation links a practitioner to a clinic, with their hours there. It
+// is a node of its own, so a practitioner can have other hours at another
+// clinic.
+type Affiliation struct {
+	ID     quad.IRI       `json:"id" quad:"@id"`
+	Clinic quad.IRI       `json:"clinic" quad:"clinic"`
+	Hours  []OpeningHours `json:"hours" quad:"schema:hoursAvailable,optional"`
+}
+
+func init() {
+	schema.RegisterType("Admin", Admin{})
+	schema.RegisterType("Clinic", Clinic{})
+	schema.RegisterType("schema:OpeningHoursSpecification", OpeningHours{})
+	schema.RegisterType("Practitioner", Practitioner{})
+	schema.RegisterType("Affiliation", Affiliation{})
+	schema.GenerateID = func(_ interface{}) quad.Value {
+		return quad.IRI(uuid.NewV1().String())
+	}
+}
+
+func main() {
+	specialty := flag.String("specialty", "cardiologist", "find practitioners with this specialty, ex: "+strings.Join(specialtyNames(), ", ")+" or a schema.org IRI; empty for all")
+	day := flag.String("day", "tuesday", "find practitioners working on this day; empty for every day")
+	registration := flag.String("practitioner", "", "list the clinics of the practitioner with this registration number, instead of searching")
+	flag.Parse()
+
+	os.RemoveAll(dbPath)
+	store := initializeAndOpenGraph(dbPath)
+	defer store.Close()
+	ctx := context.TODO()
+
+	insertSampleData(store)
+
+	if *registration != "" {
+		id, err := findPractitionerID(ctx, store, *registration)
+		checkErr(err)
+		clinics, err := ClinicsOf(ctx, store, id)
+		checkErr(err)
+		for _, c := range clinics {
+			fmt.Println(c.Name)
+		}
+		return
+	}
+
+	var s Search
+	if *specialty != "" {
+		iri, ok := Specialties[*specialty]
+		if !ok {
+			iri = quad.IRI(*specialty)
+		}
+		s.Specialty = iri
+	}
+	if *day != "" {
+		iri, ok := dayOf(*day)
+		if !ok {
+			log.Fatalf("unknown day %q", *day)
+		}
+		s.Day = iri
+	}
+
+	matches, err := s.Run(ctx, store)
+	checkErr(err)
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Clinic.Name != matches[j].Clinic.Name {
+			return matches[i].Clinic.Name < matches[j].Clinic.Name
+		}
+		return matches[i].Practitioner.Name < matches[j].Practitioner.Name
+	})
+	for _, m := range matches {
+		fmt.Printf("%s: %s (%s), %s\n", m.Clinic.Name, m.Practitioner.Name, m.Practitioner.RegistrationNumber, hoursText(m.Affiliation.Hours, s.Day))
+	}
+}
+
+func specialtyNames() []string {
+	var names []string
+	for name := range Specialties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hoursText writes opening hours, ex: Tuesday 09:00–12:00, only the ones of
+// a day if it is not empty.
+func hoursText(hours []OpeningHours, day quad.IRI) string {
+	hours = append([]OpeningHours(nil), hours...) // sorted without changing the caller's
+	sort.Slice(hours, func(i, j int) bool { return hours[i].Slot < hours[j].Slot })
+	var s []string
+	for _, h := range hours {
+		if day == "" || h.DayOfWeek == day {
+			s = append(s, fmt.Sprintf("%s %s–%s", strings.TrimPrefix(string(h.DayOfWeek), "schema:"), h.Opens, h.Closes))
+		}
+	}
+	return strings.Join(s, ", ")
+}
+
+func checkErr(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func initializeAndOpenGraph(dbFile string) *cayley.Handle {
+	graph.InitQuadStore("bolt", dbFile, nil)
+
+	// Open and use the database
+	store, err := cayley.NewGraph("bolt", dbFile, nil)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return store
+}
+
+// weekdays returns the opening hours of a slot from Monday to Friday.
+func weekdays(opens, closes string) []OpeningHours {
+	var hours []OpeningHours
+	for _, d := range []quad.IRI{"schema:Monday", "schema:Tuesday", "schema:Wednesday", "schema:Thursday", "schema:Friday"} {
+		hours = append(hours, OpeningHours{DayOfWeek: d, Slot: 1, Opens: opens, Closes: closes})
+	}
+	return hours
+}
+
+// insertSampleData creates an admin, two clinics and practitioners who work
+// at one or both of them.
+func insertSampleData(store *cayley.Handle) {
+	a := Admin{
+		Name:           "Josh",
+		Email:          "josh_f@gmail.com",
+		HashedPassword: "435iue8uou9eu",
+	}
+	_, err := insert(store, a)
+	checkErr(err)
+
+	adminId, err := findAdminID(store, a.Email)
+	checkErr(err)
+
+	healthy, err := insert(store, Clinic{
+		ID:        newID(),
+		Name:      "Healthy Life",
+		Address1:  "11 boar st, Singapore 11233",
+		CreatedBy: adminId,
+		OfficeTel: "65 6100 0939",
+		Hours:     weekdays("08:00", "18:00"),
+	})
+	checkErr(err)
+	healNow, err := insert(store, Clinic{
+		ID:        newID(),
+		Name:      "Heal Now",
+		Address1:  "3234 Rot Road, Singapore",
+		CreatedBy: adminId,
+		OfficeTel: "65 6200 1234",
+		Hours: []OpeningHours{
+			{DayOfWeek: "schema:Monday", Slot: 1, Opens: "09:00", Closes: "17:00"},
+			{DayOfWeek: "schema:Thursday", Slot: 1, Opens: "09:00", Closes: "17:00"},
+		},
+	})
+	checkErr(err)
+
+	practitioners := []Practitioner{
+		{
+			Name:               "Dr Tan",
+			RegistrationNumber: "M12345A",
+			Specialties:        []quad.IRI{"schema:Cardiovascular"},
+			Affiliations: []Affiliation{
+				{Clinic: healthy.(quad.IRI), Hours: []OpeningHours{
+					{DayOfWeek: "schema:Tuesday", Slot: 1, Opens: "09:00", Closes: "12:00"},
+					{DayOfWeek: "schema:Friday", Slot: 1, Opens: "14:00", Closes: "18:00"},
+				}},
+				{Clinic: healNow.(quad.IRI), Hours: []OpeningHours{
+					{DayOfWeek: "schema:Thursday", Slot: 1, Opens: "09:00", Closes: "17:00"},
+				}},
+			},
+		},
+		{
+			Name:               "Dr Lim",
+			RegistrationNumber: "M23456B",
+			Specialties:        []quad.IRI{"schema:Cardiovascular", "schema:PrimaryCare"},
+			Affiliations: []Affiliation{
+				{Clinic: healNow.(quad.IRI), Hours: []OpeningHours{
+					{DayOfWeek: "schema:Monday", Slot: 1, Opens: "09:00", Closes: "17:00"},
+					// the clinic is closed on Tuesday: not found on Tuesday
+					{DayOfWeek: "schema:Tuesday", Slot: 1, Opens: "09:00", Closes: "17:00"},
+				}},
+			},
+		},
+		{
+			Name:               "Dr Wong",
+			RegistrationNumber: "M34567C",
+			Specialties:        []quad.IRI{"schema:Pediatric"},
+			Affiliations: []Affiliation{
+				{Clinic: healthy.(quad.IRI), Hours: weekdays("08:00", "13:00")},
+			},
+		},
+	}
+	for _, p := range practitioners {
+		p.ID = newID()
+		for i := range p.Affiliations {
+			p.Affiliations[i].ID = newID()
+		}
+		_, err := insert(store, p)
+		checkErr(err)
+	}
+}
+
+// newID returns an ID for a new object. ID fields are written as they are,
+// so every object without one would be <>.
+func newID() quad.IRI {
+	return quad.IRI(uuid.NewV1().String())
+}
+
+func insert(h *cayley.Handle, o interface{}) (quad.Value, error) {
+	qw := graph.NewWriter(h)
+	id, err := schema.WriteAsQuads(qw, o)
+	// don't forget to close a writer; it has some internal buffering, and
+	// writing what is left of it can fail too
+	if cerr := qw.Close(); err == nil {
+		err = cerr
+	}
+	return id, err
+}
+
+func findAdminID(store *cayley.Handle, email string) (quad.IRI, error) {
+	p := cayley.StartPath(store).Has(quad.IRI("email"), quad.String(email))
+	id, err := p.Iterate(nil).FirstValue(nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	return id.(quad.IRI), nil
+}
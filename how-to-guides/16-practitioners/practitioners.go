@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph/path"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	"github.com/cayleygraph/cayley/voc/rdf"
+)
+
+// The predicates between practitioners, affiliations and clinics:
+//
+//	<practitioner> <affiliation> <affiliation> .
+//	<affiliation> <clinic> <clinic> .
+//	<affiliation> <schema:hoursAvailable> <hours> .
+//	<practitioner> <schema:medicalSpecialty> <schema:Cardiovascular> .
+var (
+	affiliationPred = quad.IRI("affiliation")
+	clinicPred      = quad.IRI("clinic")
+	hoursPred       = quad.IRI("schema:hoursAvailable")
+	specialtyPred   = quad.IRI("schema:medicalSpecialty")
+	openingPred     = quad.IRI("schema:openingHoursSpecification")
+	dayPred         = quad.IRI("schema:dayOfWeek")
+)
+
+// Specialties maps the names used on the command line to the schema.org
+// medical specialties.
+var Specialties = map[string]quad.IRI{
+	"cardiologist":  "schema:Cardiovascular",
+	"dermatologist": "schema:Dermatology",
+	"gp":            "schema:PrimaryCare",
+	"paediatrician": "schema:Pediatric",
+	"psychiatrist":  "schema:Psychiatric",
+}
+
+// dayOf returns the schema.org day of a name, ex: tuesday or Tue.
+func dayOf(name string) (quad.IRI, bool) {
+	for _, d := range []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"} {
+		if strings.EqualFold(name, d) || strings.EqualFold(name, d[:3]) {
+			return quad.IRI("schema:" + d), true
+		}
+	}
+	return "", false
+}
+
+// Search finds the practitioners of clinics, ex: the clinics with a
+// cardiologist on Tuesday:
+//
+//	Search{Specialty: "schema:Cardiovascular", Day: "schema:Tuesday"}
+//
+// Empty fields match everything.
+type Search struct {
+	Specialty quad.IRI
+	Day       quad.IRI // the clinic is open, and the practitioner works there, on that day
+	Clinic    quad.IRI
+}
+
+// Path goes from the clinics through the affiliations to the practitioners.
+// The clinics are tagged "clinic", the affiliations "affiliation" and the
+// practitioners "practitioner".
+func (s Search) Path(store *cayley.Handle) *path.Path {
+	var p *path.Path
+	if s.Clinic != "" {
+		p = cayley.StartPath(store, s.Clinic)
+	} else {
+		p = cayley.StartPath(store).Has(quad.IRI(rdf.Type), quad.IRI("Clinic"))
+	}
+	p = p.Tag("clinic")
+	if s.Day != "" {
+		p = p.Out(openingPred).Has(dayPred, s.Day).Back("clinic")
+	}
+	p = p.In(clinicPred).Has(quad.IRI(rdf.Type), quad.IRI("Affiliation")).Tag("affiliation")
+	if s.Day != "" {
+		p = p.Out(hoursPred).Has(dayPred, s.Day).Back("affiliation")
+	}
+	p = p.In(affiliationPred).Tag("practitioner")
+	if s.Specialty != "" {
+		p = p.Has(specialtyPred, s.Specialty)
+	}
+	return p
+}
+
+// Match is a practitioner found by a search, at one of their clinics.
+type Match struct {
+	Clinic       Clinic
+	Practitioner Practitioner
+	Affiliation  Affiliation // with the hours at the clinic
+}
+
+// Run returns the practitioners found by a search, by clinic.
+func (s Search) Run(ctx context.Context, store *cayley.Handle) ([]Match, error) {
+	type ids struct{ clinic, practitioner, affiliation quad.Value }
+	var found []ids
+	seen := make(map[ids]bool)
+	err := s.Path(store).Iterate(ctx).TagValues(nil, func(tags map[string]quad.Value) {
+		m := ids{tags["clinic"], tags["practitioner"], tags["affiliation"]}
+		if !seen[m] { // the same match can be found through several slots
+			seen[m] = true
+			found = append(found, m)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	clinics := make(map[quad.Value]Clinic)
+	matches := make([]Match, 0, len(found))
+	for _, f := range found {
+		c, ok := clinics[f.clinic]
+		if !ok {
+			if err := schema.LoadTo(ctx, store, &c, f.clinic); err != nil {
+				return nil, fmt.Errorf("clinic %v: %v", f.clinic, err)
+			}
+			clinics[f.clinic] = c
+		}
+		var p Practitioner
+		if err := schema.LoadTo(ctx, store, &p, f.practitioner); err != nil {
+			return nil, fmt.Errorf("practitioner %v: %v", f.practitioner, err)
+		}
+		m := Match{Clinic: c, Practitioner: p}
+		for _, a := range p.Affiliations {
+			if a.ID == f.affiliation {
+				m.Affiliation = a
+			}
+		}
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
+
+// ClinicsOf returns the clinics a practitioner works at.
+func ClinicsOf(ctx context.Context, store *cayley.Handle, practitioner quad.IRI) ([]Clinic, error) {
+	p := cayley.StartPath(store, practitioner).Out(affiliationPred).Out(clinicPred).Unique()
+	var clinics []Clinic
+	err := schema.LoadPathTo(ctx, store, &clinics, p)
+	return clinics, err
+}
+
+// findPractitionerID returns the practitioner with a registration number,
+// which is unique.
+func findPractitionerID(ctx context.Context, store *cayley.Handle, registration string) (quad.IRI, error) {
+	p := cayley.StartPath(store).Has(quad.IRI("registrationNumber"), quad.String(registration))
+	id, err := p.Iterate(ctx).FirstValue(nil)
+	if err != nil {
+		return "", err
+	} else if id == nil {
+		return "", fmt.Errorf("no practitioner with registration number %q", registration)
+	}
+	return id.(quad.IRI), nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cayleygraph/cayley/quad"
+)
+
+// PostalAddress is a schema:PostalAddress, a node of its own linked from the
+// clinic with schema:address.
+type PostalAddress struct {
+	ID         quad.IRI `json:"-" quad:"@id"`
+	Street     string   `json:"street" quad:"schema:streetAddress"`                     // ex: 585 North Bridge Road, Raffles Hospital
+	Unit       string   `json:"unit,omitempty" quad:"unit,optional"`                    // ex: #05-01
+	PostalCode string   `json:"postalCode,omitempty" quad:"schema:postalCode,optional"` // ex: 188770
+	Country    string   `json:"country" quad:"schema:addressCountry"`                   // ISO 3166-1 alpha-2 code, ex: SG
+}
+
+// String writes the address on one line, the way it is written in
+// Singapore, ex: 585 North Bridge Road, #05-01, Singapore 188770.
+func (a PostalAddress) String() string {
+	parts := []string{a.Street}
+	if a.Unit != "" {
+		parts = append(parts, a.Unit)
+	}
+	country := a.Country
+	if country == "SG" {
+		country = "Singapore"
+	}
+	if a.PostalCode != "" {
+		country += " " + a.PostalCode
+	}
+	return strings.Join(append(parts, country), ", ")
+}
+
+// UnmarshalJSON reads an address written as an object, or as one string
+// like the addresses of the earlier guides, see ParseAddress.
+func (a *PostalAddress) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		parsed, err := ParseAddress(text)
+		if err != nil {
+			return fmt.Errorf("address %q: %v", text, err)
+		}
+		*a = parsed
+		return nil
+	}
+
+	type plain PostalAddress // without this method
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields() // ex: "postcode" instead of "postalCode"
+	if err := dec.Decode((*plain)(a)); err != nil {
+		return fmt.Errorf("address: %v", err)
+	}
+	if a.Country == "" {
+		a.Country = "SG"
+	}
+	if a.PostalCode != "" && a.Country == "SG" && !ValidPostalCode(a.PostalCode) {
+		return fmt.Errorf("address: invalid postal code %q", a.PostalCode)
+	}
+	return nil
+}
+
+var ErrNoStreet = errors.New("no street")
+
+var (
+	// unit numbers are #floor-unit, ex: #02-15 or #B1-07A
+	unitRe = regexp.MustCompile(`#\s*(B?\d{1,3})\s*-\s*(\d{1,5}[A-Za-z]?)\b`)
+	// a postal code at the end, ex: Singapore 188770, S(188770) or 188770
+	postalCodeRe = regexp.MustCompile(`(?i)(?:\bsingapore\s*|\bs\s*)?\(?(\d{4,7})\)?$`)
+	countryRe    = regexp.MustCompile(`(?i)\bsingapore$`)
+)
+
+// ParseAddress parses a free-text Singapore address, ex: "11 boar st,
+// Singapore 11233" or "Blk 123 Ang Mo Kio Ave 3 #02-15 S(560123)". The unit
+// and postal code can be anywhere and are optional; what is left is the
+// street. It returns an error if there is no street or the postal code is
+// invalid, see ValidPostalCode.
+func ParseAddress(s string) (PostalAddress, error) {
+	a := PostalAddress{Country: "SG"}
+	s = strings.Join(strings.Fields(s), " ")
+
+	if m := unitRe.FindStringSubmatchIndex(s); m != nil {
+		floor, unit := strings.ToUpper(s[m[2]:m[3]]), strings.ToUpper(s[m[4]:m[5]])
+		if len(floor) == 1 {
+			floor = "0" + floor
+		}
+		a.Unit = "#" + floor + "-" + unit
+		s = s[:m[0]] + "," + s[m[1]:]
+	}
+
+	// the postal code and the country, from the end
+	for {
+		s = strings.TrimRight(s, " ,")
+		if m := postalCodeRe.FindStringSubmatchIndex(s); m != nil && a.PostalCode == "" {
+			a.PostalCode = s[m[2]:m[3]]
+			s = s[:m[0]]
+		} else if m := countryRe.FindStringIndex(s); m != nil {
+			s = s[:m[0]]
+		} else {
+			break
+		}
+	}
+
+	// the parts left, without the ones emptied by the unit
+	var street []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			street = append(street, part)
+		}
+	}
+	a.Street = strings.Join(street, ", ")
+
+	if a.Street == "" {
+		return a, ErrNoStreet
+	}
+	if a.PostalCode != "" && !ValidPostalCode(a.PostalCode) {
+		return a, fmt.Errorf("invalid postal code %q: 6 digits, starting with a postal sector from 01 to 82", a.PostalCode)
+	}
+	return a, nil
+}
+
+// ValidPostalCode tells if a Singapore postal code is valid: 6 digits, the
+// first two being a postal sector from 01 to 82, except 74 that is not used.
+func ValidPostalCode(code string) bool {
+	if len(code) != 6 {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	sector := int(code[0]-'0')*10 + int(code[1]-'0')
+	return sector >= 1 && sector <= 82 && sector != 74
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+)
+
+// The types of contact points.
+const (
+	Office    = "office"
+	Emergency = "emergency"
+	Fax       = "fax"
+	Email     = "email"
+)
+
+// ContactPoint is a schema:ContactPoint, a way to reach a clinic. Phone
+// and fax numbers are in E.164, ex: +6561000939, so the same number is
+// always written the same way; see NormalizePhone.
+type ContactPoint struct {
+	ID          quad.IRI `json:"-" quad:"@id"`
+	ContactType string   `json:"type" quad:"schema:contactType"`                       // Office, Emergency, Fax or Email
+	Telephone   string   `json:"telephone,omitempty" quad:"schema:telephone,optional"` // Office and Emergency
+	FaxNumber   string   `json:"faxNumber,omitempty" quad:"schema:faxNumber,optional"`
+	Email       string   `json:"email,omitempty" quad:"schema:email,optional"`
+}
+
+func (c ContactPoint) String() string {
+	return c.ContactType + ": " + c.value()
+}
+
+// value is the number or email of the contact point.
+func (c ContactPoint) value() string {
+	switch c.ContactType {
+	case Fax:
+		return c.FaxNumber
+	case Email:
+		return c.Email
+	}
+	return c.Telephone
+}
+
+// predicate is where value is stored.
+func (c ContactPoint) predicate() quad.IRI {
+	switch c.ContactType {
+	case Fax:
+		return quad.IRI("schema:faxNumber")
+	case Email:
+		return quad.IRI("schema:email")
+	}
+	return quad.IRI("schema:telephone")
+}
+
+// NewContactPoint returns a contact point with an ID of its own, and its
+// number or email normalized. country is the ISO 3166-1 code used for numbers written
+// without their country code, ex: SG for 6100 0939.
+func NewContactPoint(contactType, value, country string) (ContactPoint, error) {
+	c := ContactPoint{ID: newID(), ContactType: contactType}
+	var err error
+	switch contactType {
+	case Office, Emergency:
+		c.Telephone, err = NormalizePhone(value, country)
+	case Fax:
+		c.FaxNumber, err = NormalizePhone(value, country)
+	case Email:
+		c.Email, err = normalizeEmail(value)
+	default:
+		err = fmt.Errorf("unknown contact type %q", contactType)
+	}
+	return c, err
+}
+
+// normalizeContacts normalizes contact points read from a file, see
+// NewContactPoint.
+func normalizeContacts(contacts []ContactPoint, country string) error {
+	for i, c := range contacts {
+		n, err := NewContactPoint(c.ContactType, c.value(), country)
+		if err != nil {
+			return err
+		}
+		if c.ID != "" {
+			n.ID = c.ID
+		}
+		contacts[i] = n
+	}
+	return nil
+}
+
+// numbering is how phone numbers are written in a country.
+type numbering struct {
+	code     string // the country calling code, ex: 65
+	trunk    string // dialled before national numbers inside the country, ex: 0 in the UK
+	lengths  []int  // of national numbers, without the trunk prefix
+	starting string // the digits national numbers start with, any when empty
+}
+
+// numberings lists the countries whose numbers can be checked. Numbers of
+// the others are accepted if they are written with their country code.
+var numberings = map[string]numbering{
+	"SG": {code: "65", lengths: []int{8}, starting: "3689"},
+	"MY": {code: "60", trunk: "0", lengths: []int{8, 9, 10}, starting: "13456789"},
+	"GB": {code: "44", trunk: "0", lengths: []int{9, 10}, starting: "123578"},
+	"US": {code: "1", trunk: "1", lengths: []int{10}, starting: "23456789"},
+}
+
+func (n numbering) valid(national string) bool {
+	if n.starting != "" && (national == "" || !strings.ContainsRune(n.starting, rune(national[0]))) {
+		return false
+	}
+	for _, l := range n.lengths {
+		if len(national) == l {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizePhone returns a phone number in E.164: + then the country code
+// and the national number, ex: "65 6100 0939", "+65 6100-0939" and
+// "6100 0939" in SG are all +6561000939. Numbers without a country code are
+// in country.
+func NormalizePhone(s, country string) (string, error) {
+	var digits strings.Builder
+	international := false
+	for i, r := range strings.TrimSpace(s) {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+			international = true
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", fmt.Errorf("phone number %q: invalid character %q", s, r)
+		}
+	}
+	d := digits.String()
+	if !international && strings.HasPrefix(d, "00") {
+		international, d = true, d[2:]
+	}
+
+	if international {
+		for _, n := range numberings {
+			if strings.HasPrefix(d, n.code) && n.valid(d[len(n.code):]) {
+				return "+" + d, nil
+			}
+		}
+		for _, n := range numberings {
+			if strings.HasPrefix(d, n.code) {
+				return "", fmt.Errorf("phone number %q: invalid number for country code %s", s, n.code)
+			}
+		}
+		if len(d) < 8 || len(d) > 15 {
+			return "", fmt.Errorf("phone number %q: E.164 numbers have 8 to 15 digits", s)
+		}
+		return "+" + d, nil
+	}
+
+	n, ok := numberings[country]
+	if !ok {
+		return "", fmt.Errorf("phone number %q: no country code, and numbers of %q can't be checked", s, country)
+	}
+	switch {
+	case n.valid(d):
+	case n.trunk != "" && strings.HasPrefix(d, n.trunk) && n.valid(d[len(n.trunk):]):
+		d = d[len(n.trunk):]
+	case strings.HasPrefix(d, n.code) && n.valid(d[len(n.code):]):
+		// the country code without +, ex: 65 6100 0939
+		d = d[len(n.code):]
+	default:
+		return "", fmt.Errorf("phone number %q: invalid number in %s", s, country)
+	}
+	return "+" + n.code + d, nil
+}
+
+// SamePhone tells if two phone numbers are the same once normalized, ex:
+// "65 6100 0939" and "+6561000939".
+func SamePhone(a, b, country string) bool {
+	na, err := NormalizePhone(a, country)
+	if err != nil {
+		return false
+	}
+	nb, err := NormalizePhone(b, country)
+	return err == nil && na == nb
+}
+
+// normalizeEmail checks an email and writes its domain in lower case, which
+// is not case sensitive; the part before @ can be.
+func normalizeEmail(s string) (string, error) {
+	a, err := mail.ParseAddress(strings.TrimSpace(s))
+	if err != nil {
+		return "", fmt.Errorf("email %q: %v", s, err)
+	}
+	at := strings.LastIndex(a.Address, "@")
+	return a.Address[:at] + strings.ToLower(a.Address[at:]), nil
+}
+
+// SetContact sets the contact point of a type of a clinic, ex: its office
+// phone. It returns false, and writes nothing, when the clinic already has
+// the same number written another way.
+func SetContact(ctx context.Context, store *cayley.Handle, clinic quad.IRI, c ContactPoint) (bool, error) {
+	p := cayley.StartPath(store, clinic).Out(quad.IRI("schema:contactPoint")).Has(quad.IRI("schema:contactType"), quad.String(c.ContactType))
+	var current []ContactPoint
+	if err := schema.LoadPathTo(ctx, store, &current, p); err != nil {
+		return false, err
+	}
+
+	tx := cayley.NewTransaction()
+	if len(current) == 0 {
+		id, err := schema.WriteAsQuads(txWriter{tx}, c)
+		if err != nil {
+			return false, err
+		}
+		tx.AddQuad(quad.Make(clinic, quad.IRI("schema:contactPoint"), id, nil))
+		return true, store.ApplyTransaction(tx)
+	}
+
+	// both are normalized, so the same number is the same string
+	old := current[0]
+	if old.value() == c.value() {
+		return false, nil
+	}
+	if old.value() != "" {
+		tx.RemoveQuad(quad.Make(old.ID, c.predicate(), old.value(), nil))
+	}
+	tx.AddQuad(quad.Make(old.ID, c.predicate(), c.value(), nil))
+	return true, store.ApplyTransaction(tx)
+}
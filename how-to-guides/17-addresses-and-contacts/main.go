@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"context"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	_ "github.com/cayleygraph/cayley/graph/kv/bolt"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	"github.com/cayleygraph/cayley/voc/rdf"
+	uuid "github.com/satori/go.uuid"
+)
+
+var dbPath = "db.boltdb"
+
+type Admin struct {
+	Name           string `json:"name" quad:"name"`
+	Email          string `json:"email" quad:"email"`
+	HashedPassword string `json:"hashedPassword"  quad:"hashed_password"`
+}
+
+type Clinic struct {
+	ID        quad.IRI       `json:"id" quad:"@id"`
+	Name      string         `json:"name" quad:"name"`
+	Address   PostalAddress  `json:"address" quad:"schema:address,optional"`
+	Address1  string         `json:"-" quad:"address,optional"` // the free-text address of the earlier guides, until MigrateAddresses converts it
+	CreatedBy quad.IRI       `quad:"createdBy"`
+	Contacts  []ContactPoint `json:"contactPoints" quad:"schema:contactPoint,optional"`
+	OfficeTel string         `json:"-" quad:"officeTel,optional"` // the phone of the earlier guides, until MigrateOfficeTel converts it
+}
+
+func init() {
+	schema.RegisterType("Admin", Admin{})
+	schema.RegisterType("Clinic", Clinic{})
+	schema.RegisterType("schema:PostalAddress", PostalAddress{})
+	schema.RegisterType("schema:ContactPoint", ContactPoint{})
+	schema.GenerateID = func(_ interface{}) quad.Value {
+		return quad.IRI(uuid.NewV1().String())
+	}
+}
+
+func main() {
+	db := flag.String("db", "", "bolt database to migrate, ex: ../05-update-clinic/db.boltdb; a new one with sample data is created when empty")
+	dryRun := flag.Bool("dry-run", false, "show what the migration would do, without changing the database")
+	flag.Parse()
+
+	var store *cayley.Handle
+	if *db == "" {
+		os.RemoveAll(dbPath)
+		store = initializeAndOpenGraph(dbPath)
+		insertSampleData(store)
+	} else {
+		store = initializeAndOpenGraph(*db)
+	}
+	defer store.Close()
+	ctx := context.TODO()
+
+	addresses, err := MigrateAddresses(ctx, store, *dryRun)
+	checkErr(err)
+	phones, err := MigrateOfficeTel(ctx, store, *dryRun)
+	checkErr(err)
+	for _, m := range append(addresses, phones...) {
+		if m.Err != nil {
+			fmt.Printf("skipped %s: %q: %v\n", m.Name, m.From, m.Err)
+		} else {
+			fmt.Printf("converted %s: %q -> %s\n", m.Name, m.From, m.To)
+		}
+	}
+	if *dryRun {
+		return
+	}
+
+	if *db == "" {
+		updatePhones(ctx, store)
+	}
+
+	var clinics []Clinic
+	checkErr(schema.LoadTo(ctx, store, &clinics))
+	sort.Slice(clinics, func(i, j int) bool { return clinics[i].Name < clinics[j].Name })
+	for _, c := range clinics {
+		fmt.Println()
+		fmt.Println(c.Name)
+		if c.Address1 != "" {
+			fmt.Printf("  not converted: %q\n", c.Address1)
+		} else {
+			fmt.Println("  street:     ", c.Address.Street)
+			fmt.Println("  unit:       ", c.Address.Unit)
+			fmt.Println("  postal code:", c.Address.PostalCode)
+			fmt.Println("  country:    ", c.Address.Country)
+		}
+		sort.Slice(c.Contacts, func(i, j int) bool { return c.Contacts[i].ContactType < c.Contacts[j].ContactType })
+		for _, cp := range c.Contacts {
+			fmt.Println("  " + cp.String())
+		}
+	}
+}
+
+// updatePhones sets the office phone of Heal Now, first to the same number
+// written another way, then to a new one.
+func updatePhones(ctx context.Context, store *cayley.Handle) {
+	id, err := cayley.StartPath(store).Has(quad.IRI("name"), quad.String("Heal Now")).Iterate(ctx).FirstValue(nil)
+	checkErr(err)
+	fmt.Println()
+	for _, tel := range []string{"+65 6200-1234", "6200 1235"} {
+		c, err := NewContactPoint(Office, tel, "SG")
+		checkErr(err)
+		changed, err := SetContact(ctx, store, id.(quad.IRI), c)
+		checkErr(err)
+		fmt.Printf("set office phone of Heal Now to %q: changed %v\n", tel, changed)
+	}
+}
+
+func checkErr(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func initializeAndOpenGraph(dbFile string) *cayley.Handle {
+	graph.InitQuadStore("bolt", dbFile, nil)
+
+	// Open and use the database
+	store, err := cayley.NewGraph("bolt", dbFile, nil)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return store
+}
+
+// insertSampleData creates clinics with free-text addresses, the way the
+// earlier guides wrote them, and clinics from JSON files with postal
+// addresses.
+func insertSampleData(store *cayley.Handle) {
+	a := Admin{
+		Name:           "Josh",
+		Email:          "josh_f@gmail.com",
+		HashedPassword: "435iue8uou9eu",
+	}
+	_, err := insert(store, a)
+	checkErr(err)
+
+	adminId, err := findAdminID(store, a.Email)
+	checkErr(err)
+
+	for _, c := range []struct{ name, address, tel string }{
+		{"Healthy Life", "11 boar st, Singapore 11233", "65 6100 0939"}, // a postal code has 6 digits
+		{"Heal Now", "3234 Rot Road, Singapore", "65 6200 1234"},
+		{"Ang Mo Kio Polyclinic", "Blk 723 Ang Mo Kio Ave 8 #01-4136 S(560723)", "6355 3000"},
+	} {
+		id := schema.GenerateID(nil)
+		qw := graph.NewWriter(store)
+		_, err := qw.WriteQuads([]quad.Quad{
+			quad.Make(id, quad.IRI(rdf.Type), quad.IRI("Clinic"), nil),
+			quad.Make(id, quad.IRI("name"), c.name, nil),
+			quad.Make(id, quad.IRI("address"), c.address, nil),
+			quad.Make(id, quad.IRI("createdBy"), adminId, nil),
+			quad.Make(id, quad.IRI("officeTel"), c.tel, nil),
+		})
+		checkErr(err)
+		checkErr(qw.Close())
+	}
+
+	for _, file := range []string{"clinic.json", "clinic-text-address.json"} {
+		c, err := loadJSON(file)
+		checkErr(err)
+		c.ID = newID()
+		c.Address.ID = newID()
+		c.CreatedBy = adminId
+		_, err = insert(store, c)
+		checkErr(err)
+	}
+}
+
+// loadJSON reads a clinic. Unknown keys are an error, so a misspelled one,
+// ex: "adress", is not silently dropped.
+func loadJSON(JSONFile string) (*Clinic, error) {
+	f, err := os.Open(JSONFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	var c Clinic
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("%s: %v", JSONFile, err)
+	}
+	if err := normalizeContacts(c.Contacts, c.Address.Country); err != nil {
+		return nil, fmt.Errorf("%s: %v", JSONFile, err)
+	}
+	return &c, nil
+}
+
+// newID returns an ID for a new object. ID fields are written as they are,
+// so every object without one would be <>.
+func newID() quad.IRI {
+	return quad.IRI(uuid.NewV1().String())
+}
+
+func insert(h *cayley.Handle, o interface{}) (quad.Value, error) {
+	qw := graph.NewWriter(h)
+	id, err := schema.WriteAsQuads(qw, o)
+	// don't forget to close a writer; it has some internal buffering, and
+	// writing what is left of it can fail too
+	if cerr := qw.Close(); err == nil {
+		err = cerr
+	}
+	return id, err
+}
+
+func findAdminID(store *cayley.Handle, email string) (quad.IRI, error) {
+	p := cayley.StartPath(store).Has(quad.IRI("email"), quad.String(email))
+	id, err := p.Iterate(nil).FirstValue(nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	return id.(quad.IRI), nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	"github.com/cayleygraph/cayley/voc/rdf"
+)
+
+// Migrated is a clinic whose literal, ex: its address, was converted, or not
+// when Err is set.
+type Migrated struct {
+	Clinic quad.Value
+	Name   string
+	From   string
+	To     fmt.Stringer // a PostalAddress or a ContactPoint
+	Err    error        // the literal could not be parsed, and was left as it is
+
+	value quad.Value // the literal as it is stored
+}
+
+// txWriter adds the quads written by schema.WriteAsQuads to a transaction.
+type txWriter struct {
+	tx *graph.Transaction
+}
+
+func (w txWriter) WriteQuad(q quad.Quad) error {
+	w.tx.AddQuad(q)
+	return nil
+}
+
+func (w txWriter) WriteQuads(buf []quad.Quad) (int, error) {
+	for _, q := range buf {
+		w.tx.AddQuad(q)
+	}
+	return len(buf), nil
+}
+
+// MigrateAddresses replaces the free-text addresses of clinics, ex:
+// <clinic> <address> "11 boar st, Singapore 11233", with a PostalAddress.
+// Every clinic is converted in a transaction of its own: the address node is
+// added and the text removed together. Addresses that can't be parsed are
+// left for someone to fix, so the migration can be run again. With dryRun,
+// nothing is written.
+func MigrateAddresses(ctx context.Context, store *cayley.Handle, dryRun bool) ([]Migrated, error) {
+	return migrate(ctx, store, quad.IRI("address"), quad.IRI("schema:address"), func(text string) (fmt.Stringer, error) {
+		a, err := ParseAddress(text)
+		a.ID = newID()
+		return a, err
+	}, dryRun)
+}
+
+// MigrateOfficeTel replaces the officeTel of clinics, added in the guide
+// to update a clinic, with an Office ContactPoint in E.164, the same way
+// MigrateAddresses does. Numbers without a country code are in Singapore.
+func MigrateOfficeTel(ctx context.Context, store *cayley.Handle, dryRun bool) ([]Migrated, error) {
+	return migrate(ctx, store, quad.IRI("officeTel"), quad.IRI("schema:contactPoint"), func(text string) (fmt.Stringer, error) {
+		return NewContactPoint(Office, text, "SG")
+	}, dryRun)
+}
+
+// migrate replaces the literals of clinics with a predicate by the nodes
+// parse makes of them.
+func migrate(ctx context.Context, store *cayley.Handle, from, to quad.IRI, parse func(string) (fmt.Stringer, error), dryRun bool) ([]Migrated, error) {
+	p := cayley.StartPath(store).Has(quad.IRI(rdf.Type), quad.IRI("Clinic")).Tag("clinic").
+		Save(from, "literal").
+		SaveOptional(quad.IRI("name"), "name")
+
+	var found []Migrated
+	err := p.Iterate(ctx).TagValues(nil, func(tags map[string]quad.Value) {
+		found = append(found, Migrated{
+			Clinic: tags["clinic"],
+			Name:   stringOf(tags["name"]),
+			From:   stringOf(tags["literal"]),
+			value:  tags["literal"],
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range found {
+		m := &found[i]
+		m.To, m.Err = parse(m.From)
+		if m.Err != nil || dryRun {
+			continue
+		}
+
+		tx := cayley.NewTransaction()
+		id, err := schema.WriteAsQuads(txWriter{tx}, m.To)
+		if err != nil {
+			return found, err
+		}
+		tx.AddQuad(quad.Make(m.Clinic, to, id, nil))
+		tx.RemoveQuad(quad.Make(m.Clinic, from, m.value, nil))
+		if err := store.ApplyTransaction(tx); err != nil {
+			return found, err
+		}
+	}
+	return found, nil
+}
+
+// stringOf returns the text of a literal. quad.StringOf doesn't: it writes
+// it the way N-Quads do, in quotes.
+func stringOf(v quad.Value) string {
+	s, _ := v.(quad.String)
+	return string(s)
+}
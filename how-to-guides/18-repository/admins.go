@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	"github.com/cayleygraph/cayley/voc/rdf"
+)
+
+var ErrAdminNotFound = errors.New("admin not found")
+
+// EmailTakenError is returned when an admin is created with the email of
+// another one.
+type EmailTakenError struct {
+	Email string
+	Admin quad.IRI // the admin that has it
+}
+
+func (e *EmailTakenError) Error() string {
+	return fmt.Sprintf("email %s is already used by admin %v", e.Email, e.Admin)
+}
+
+// NormalizeEmail checks the syntax of an email and writes it in lower case,
+// ex: " Josh_F@Gmail.com" is josh_f@gmail.com. Only the domain is case
+// insensitive for the RFC, but mail providers ignore the case of the part
+// before @ too, and two admins whose emails differ only by case are a
+// mistake.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	a, err := mail.ParseAddress(email)
+	if err != nil || a.Address != email { // no name, ex: Josh <josh_f@gmail.com>
+		return "", fmt.Errorf("invalid email %q", email)
+	}
+	return strings.ToLower(email), nil
+}
+
+// emailClaim is a quad that is the same for every admin with an email. The
+// store refuses to add a quad twice, so two admins created at the same time
+// with one email can't both be written.
+func emailClaim(email string) quad.Quad {
+	return quad.Make(quad.IRI("mailto:"+email), quad.IRI(rdf.Type), quad.IRI("AdminEmail"), nil)
+}
+
+// CreateAdmin adds an admin with a normalized email, and returns its ID. It
+// fails with an *EmailTakenError if another admin has the same email, in any
+// case.
+func (r *Repository) CreateAdmin(ctx context.Context, a Admin) (quad.IRI, error) {
+	u := r.Begin()
+	id, err := u.Add(&a)
+	if err != nil {
+		return "", err
+	}
+	return id, u.Commit(ctx)
+}
+
+// GetAdmin returns an admin, from the Cache if it is there. Its Version is
+// the one to give to UpdateAdmin.
+func (r *Repository) GetAdmin(ctx context.Context, id quad.IRI) (Admin, error) {
+	if v, ok := r.Cache.get(id); ok {
+		if a, ok := v.(Admin); ok {
+			return a, nil
+		}
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	gen := r.Cache.generation()
+	var a Admin
+	err := schema.LoadTo(ctx, r.store, &a, id)
+	if schema.IsNotFound(err) {
+		return a, ErrAdminNotFound
+	} else if err != nil {
+		return a, err
+	}
+	r.Cache.put(id, a, nil, gen)
+	return a, nil
+}
+
+// UpdateAdmin replaces an admin by a, and returns its new version. expected
+// is the version a was read at: if the admin was updated since, nothing is
+// written and the error is a *ConflictError. A new email is normalized and
+// must not be used by another admin, see CreateAdmin. It fails with
+// ErrAdminNotFound if a.ID is not an admin.
+func (r *Repository) UpdateAdmin(ctx context.Context, a Admin, expected int) (int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	email, err := NormalizeEmail(a.Email)
+	if err != nil {
+		return 0, err
+	}
+	a.Email = email
+	if id, err := r.FindAdminID(ctx, email); err == nil && id != a.ID {
+		return 0, &EmailTakenError{Email: email, Admin: id}
+	} else if err != nil && err != ErrAdminNotFound {
+		return 0, err
+	}
+
+	old, err := r.GetAdmin(ctx, a.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	a.Version = expected + 1
+	tx := cayley.NewTransaction()
+	err = r.replace(ctx, tx, a.ID, quad.IRI("Admin"), expected, a)
+	if err == errWrongType {
+		return 0, ErrAdminNotFound
+	} else if err != nil {
+		return 0, err
+	}
+	if old.Email != email {
+		tx.AddQuad(emailClaim(email))
+		if r.store.ValueOf(emailClaim(old.Email).Subject) != nil {
+			tx.RemoveQuad(emailClaim(old.Email))
+		}
+	}
+	err = r.applyUpdate(ctx, tx, a.ID, expected)
+	if graph.IsQuadExist(err) {
+		// the claim of the new email, if it is another admin's
+		if other, ferr := r.FindAdminID(ctx, email); ferr == nil && other != a.ID {
+			return 0, &EmailTakenError{Email: email, Admin: other}
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return a.Version, nil
+}
+
+// FindAdminID returns the admin with an email, in any case. The emails of
+// admins written before the repository must have been normalized first, see
+// MigrateEmails.
+func (r *Repository) FindAdminID(ctx context.Context, email string) (quad.IRI, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return "", err
+	}
+
+	p := cayley.StartPath(r.store).Has(quad.IRI("email"), quad.String(email))
+	id, err := p.Iterate(ctx).FirstValue(nil)
+	if err != nil {
+		return "", err
+	} else if id == nil {
+		return "", ErrAdminNotFound
+	}
+	return id.(quad.IRI), nil
+}
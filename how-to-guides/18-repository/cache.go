@@ -0,0 +1,158 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+)
+
+// Cache keeps the admins and clinics the repository loaded, by ID, so
+// reading one again doesn't query the store. An object is removed when the
+// repository writes a quad about it or about a node it owns, ex: one of the
+// opening hours of a clinic. Writes made without the repository, ex: by
+// another program, are not seen.
+//
+// A nil *Cache caches nothing.
+type Cache struct {
+	max int
+
+	mu      sync.Mutex
+	lru     *list.List                 // of *cached, the most recently used first
+	objects map[quad.IRI]*list.Element // by ID
+	owners  map[quad.Value]quad.IRI    // the object that owns a node, ex: the clinic of opening hours
+	gen     int                        // incremented by every write, see generation
+	stats   CacheStats
+}
+
+// CacheStats counts what happened to a cache since it was created.
+type CacheStats struct {
+	Hits          int
+	Misses        int
+	Evictions     int // objects removed to make room for others
+	Invalidations int // objects removed because they were written
+	Size          int // objects in the cache now
+}
+
+type cached struct {
+	id       quad.IRI
+	value    interface{} // Admin or Clinic
+	children []quad.Value
+}
+
+// NewCache returns a cache of up to max objects. The ones used the least
+// recently are removed first to make room.
+func NewCache(max int) *Cache {
+	return &Cache{
+		max:     max,
+		lru:     list.New(),
+		objects: make(map[quad.IRI]*list.Element),
+		owners:  make(map[quad.Value]quad.IRI),
+	}
+}
+
+// Stats returns the counts of the cache.
+func (c *Cache) Stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Size = c.lru.Len()
+	return s
+}
+
+func (c *Cache) get(id quad.IRI) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.objects[id]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.lru.MoveToFront(e)
+	return e.Value.(*cached).value, true
+}
+
+// generation returns a number that changes with every write. It is taken
+// before loading an object, and given to put: if the object was written
+// meanwhile, what was loaded may be older than the write, and is not kept.
+func (c *Cache) generation() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+func (c *Cache) put(id quad.IRI, v interface{}, children []quad.Value, gen int) {
+	if c == nil || c.max <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	c.remove(id)
+	c.objects[id] = c.lru.PushFront(&cached{id: id, value: v, children: children})
+	for _, child := range children {
+		c.owners[child] = id
+	}
+	for c.lru.Len() > c.max {
+		c.remove(c.lru.Back().Value.(*cached).id)
+		c.stats.Evictions++
+	}
+}
+
+// remove removes an object, and forgets the nodes it owns.
+func (c *Cache) remove(id quad.IRI) bool {
+	e, ok := c.objects[id]
+	if !ok {
+		return false
+	}
+	for _, child := range e.Value.(*cached).children {
+		delete(c.owners, child)
+	}
+	c.lru.Remove(e)
+	delete(c.objects, id)
+	return true
+}
+
+// invalidate removes the objects that a transaction writes, or whose nodes
+// it writes.
+func (c *Cache) invalidate(tx *graph.Transaction) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for _, d := range tx.Deltas {
+		id, ok := c.owners[d.Quad.Subject]
+		if !ok {
+			id, _ = d.Quad.Subject.(quad.IRI)
+		}
+		if c.remove(id) {
+			c.stats.Invalidations++
+		}
+	}
+}
+
+// children returns the nodes an object owns, see owned.
+func (r *Repository) children(ctx context.Context, id quad.IRI) ([]quad.Value, error) {
+	var preds []interface{}
+	for p := range owned {
+		preds = append(preds, p)
+	}
+	return cayley.StartPath(r.store, id).Out(preds...).Iterate(ctx).AllValues(nil)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+)
+
+var ErrClinicNotFound = errors.New("clinic not found")
+
+// CreateClinic adds a clinic, at version 1, and returns its ID. To add it
+// with its admin, see UnitOfWork.
+func (r *Repository) CreateClinic(ctx context.Context, c Clinic) (quad.IRI, error) {
+	u := r.Begin()
+	id, err := u.Add(&c)
+	if err != nil {
+		return "", err
+	}
+	return id, u.Commit(ctx)
+}
+
+// GetClinic returns a clinic with its opening hours, from the Cache if it
+// is there. Its Version is the one to give to UpdateClinic.
+func (r *Repository) GetClinic(ctx context.Context, id quad.IRI) (Clinic, error) {
+	if v, ok := r.Cache.get(id); ok {
+		if c, ok := v.(Clinic); ok {
+			return c.clone(), nil
+		}
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	gen := r.Cache.generation()
+	var c Clinic
+	err := schema.LoadTo(ctx, r.store, &c, id)
+	if schema.IsNotFound(err) {
+		return c, ErrClinicNotFound
+	} else if err != nil {
+		return c, err
+	}
+	if r.Cache != nil {
+		children, err := r.children(ctx, id)
+		if err != nil {
+			return c, err
+		}
+		r.Cache.put(id, c.clone(), children, gen)
+	}
+	return c, nil
+}
+
+// clone returns a copy of c that doesn't share its opening hours, so the
+// one in the cache can't be changed by appending to the other.
+func (c Clinic) clone() Clinic {
+	c.Hours = append([]OpeningHours(nil), c.Hours...)
+	return c
+}
+
+// UpdateClinic replaces a clinic, and its opening hours, by c, and returns
+// its new version. expected is the version c was read at: if the clinic was
+// updated since, nothing is written and the error is a *ConflictError; read
+// it again, apply the change again, and retry. It fails with
+// ErrClinicNotFound if id is not a clinic.
+func (r *Repository) UpdateClinic(ctx context.Context, c Clinic, expected int) (int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	c.Version = expected + 1
+	tx := cayley.NewTransaction()
+	err := r.replace(ctx, tx, c.ID, quad.IRI("Clinic"), expected, c)
+	if err == errWrongType {
+		return 0, ErrClinicNotFound
+	} else if err != nil {
+		return 0, err
+	}
+	if err := r.applyUpdate(ctx, tx, c.ID, expected); err != nil {
+		return 0, err
+	}
+	return c.Version, nil
+}
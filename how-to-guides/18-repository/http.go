@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+)
+
+// ClinicsHandler serves the clinics of a repository as JSON:
+//
+//	GET /clinics/<id>
+//	PUT /clinics/<id>
+//
+// The ETag of a clinic is its version. A PUT must have the ETag of the
+// clinic it changes in If-Match, so it doesn't overwrite a change it hasn't
+// seen: it fails with 412 Precondition Failed if the clinic was updated
+// since, and with 428 Precondition Required without If-Match. If-Match: *
+// matches any version of the clinic, but not a clinic that doesn't exist.
+// A clinic that can't be written, ex: without a name, is a 400 Bad
+// Request.
+//
+// Requests stop when the client goes away, and fail with 503 Service
+// Unavailable after Timeout, if it is not 0.
+type ClinicsHandler struct {
+	Repo    *Repository
+	Timeout time.Duration
+}
+
+func (h ClinicsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	id := quad.IRI(strings.TrimPrefix(req.URL.Path, "/clinics/"))
+	if id == "" {
+		http.NotFound(w, req)
+		return
+	}
+	if h.Timeout > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), h.Timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		h.get(w, req, id)
+	case http.MethodPut:
+		h.put(w, req, id)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (h ClinicsHandler) get(w http.ResponseWriter, req *http.Request, id quad.IRI) {
+	c, err := h.Repo.GetClinic(req.Context(), id)
+	if err == ErrClinicNotFound {
+		http.NotFound(w, req)
+		return
+	} else if err != nil {
+		serverError(w, err)
+		return
+	}
+	etag := etagOf(c.Version)
+	if req.Header.Get("If-None-Match") == etag {
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeClinic(w, c)
+}
+
+func (h ClinicsHandler) put(w http.ResponseWriter, req *http.Request, id quad.IRI) {
+	match := req.Header.Get("If-Match")
+	if match == "" {
+		http.Error(w, "If-Match is required, with the ETag of the clinic", http.StatusPreconditionRequired)
+		return
+	}
+	var expected int
+	if match == "*" {
+		// any version, as long as the clinic exists
+		c, err := h.Repo.GetClinic(req.Context(), id)
+		if err == ErrClinicNotFound {
+			http.Error(w, "no clinic matches If-Match: *", http.StatusPreconditionFailed)
+			return
+		} else if err != nil {
+			serverError(w, err)
+			return
+		}
+		expected = c.Version
+	} else if v, ok := versionOf(match); ok {
+		expected = v
+	} else {
+		http.Error(w, "If-Match is not the ETag of a clinic", http.StatusPreconditionFailed)
+		return
+	}
+
+	dec := json.NewDecoder(req.Body)
+	dec.DisallowUnknownFields()
+	var c Clinic
+	if err := dec.Decode(&c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.ID = id
+
+	version, err := h.Repo.UpdateClinic(req.Context(), c, expected)
+	if _, ok := err.(*ConflictError); ok {
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		return
+	} else if err == ErrClinicNotFound {
+		http.NotFound(w, req)
+		return
+	} else if invalid(err) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		serverError(w, err)
+		return
+	}
+	c.Version = version
+	writeClinic(w, c)
+}
+
+// invalid tells if a write failed because of the object it was given, ex:
+// a clinic without a name, and not because of the server.
+func invalid(err error) bool {
+	var notSet schema.ErrReqFieldNotSet
+	return errors.As(err, &notSet)
+}
+
+// serverError answers a request that failed on the server side.
+func serverError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		// the client went away
+	case errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, "timeout", http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeClinic(w http.ResponseWriter, c Clinic) {
+	w.Header().Set("ETag", etagOf(c.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
+
+// etagOf returns the ETag of a version, ex: "3".
+func etagOf(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// versionOf returns the version of an ETag; W/ is ignored since versions
+// change with every write.
+func versionOf(etag string) (int, bool) {
+	s, err := strconv.Unquote(strings.TrimPrefix(etag, "W/"))
+	if err != nil {
+		return 0, false
+	}
+	v, err := strconv.Atoi(s)
+	return v, err == nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	_ "github.com/cayleygraph/cayley/graph/kv/bolt"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	uuid "github.com/satori/go.uuid"
+)
+
+var dbPath = "db.boltdb"
+
+type Admin struct {
+	ID             quad.IRI `json:"id" quad:"@id"`
+	Name           string   `json:"name" quad:"name"`
+	Email          string   `json:"email" quad:"email"` // normalized, see NormalizeEmail
+	HashedPassword string   `json:"hashedPassword"  quad:"hashed_password"`
+	Version        int      `json:"-" quad:"version,optional"` // see UpdateAdmin
+}
+
+type Clinic struct {
+	ID        quad.IRI       `json:"id" quad:"@id"`
+	Name      string         `json:"name" quad:"name"`
+	Address1  string         `json:"address" quad:"address"`
+	CreatedBy quad.IRI       `quad:"createdBy"`
+	Creator   *Admin         `json:"-" quad:"-"` // an admin added in the same unit of work, see UnitOfWork
+	OfficeTel string         `json:"officeTel" quad:"officeTel"`
+	Hours     []OpeningHours `json:"hours" quad:"schema:openingHoursSpecification"`
+	Version   int            `json:"-" quad:"version,optional"` // see UpdateClinic; the ETag over HTTP
+}
+
+type OpeningHours struct {
+	DayOfWeek quad.IRI `json:"day" quad:"schema:dayOfWeek"`
+	Slot      int      `json:"slot" quad:"slot"`
+	Opens     string   `json:"opens" quad:"schema:opens"` // ex: 12:00 or 12:00:00
+	Closes    string   `json:"closes" quad:"schema:closes"`
+}
+
+func init() {
+	schema.RegisterType("Admin", Admin{})
+	schema.RegisterType("Clinic", Clinic{})
+	schema.RegisterType("schema:OpeningHoursSpecification", OpeningHours{})
+	schema.GenerateID = func(_ interface{}) quad.Value {
+		return quad.IRI(uuid.NewV1().String())
+	}
+}
+
+func main() {
+	addr := flag.String("http", "", "serve the clinics on this address, ex: localhost:8080")
+	cacheSize := flag.Int("cache", 1000, "admins and clinics kept in memory once read; 0 to read them from the database every time")
+	timeout := flag.Duration("timeout", 5*time.Second, "of each read or write of the repository, and each HTTP request")
+	flag.Parse()
+
+	ctx := context.Background()
+	os.RemoveAll(dbPath)
+	store := initializeAndOpenGraph(dbPath)
+	store = reopenWhileLocked(ctx, store, dbPath)
+	defer store.Close()
+	repo := NewRepository(store)
+	repo.Timeout = *timeout
+	repo.Cache = NewCache(*cacheSize)
+
+	adminId, err := repo.CreateAdmin(ctx, Admin{
+		Name:           "Josh",
+		Email:          " Josh_F@Gmail.com",
+		HashedPassword: "435iue8uou9eu",
+	})
+	checkErr(err)
+	fmt.Println("created admin", adminId)
+
+	// an admin written by the earlier guides, without normalizing the email
+	_, err = insert(store, Admin{ID: quad.IRI(uuid.NewV1().String()), Name: "Mary", Email: "Mary.Tan@Example.com", HashedPassword: "9eu8ouoi35"})
+	checkErr(err)
+	// and another one with Josh's email, which he keeps
+	_, err = insert(store, Admin{ID: quad.IRI(uuid.NewV1().String()), Name: "Joshua", Email: "JOSH_F@gmail.com", HashedPassword: "ou9eu8oi35"})
+	checkErr(err)
+	for i := 0; i < 2; i++ { // the second time, there is nothing left to do
+		migrated, err := repo.MigrateEmails(ctx, false)
+		checkErr(err)
+		fmt.Printf("migrate emails: %d admins\n", len(migrated))
+		for _, m := range migrated {
+			fmt.Printf("  %s -> %s: %v\n", m.From, m.To, m.Err)
+		}
+	}
+
+	for _, email := range []string{"josh_f@gmail.com", "JOSH_F@GMAIL.COM", "mary.tan@example.com", "doe@example.com", "josh_f@"} {
+		id, err := repo.FindAdminID(ctx, email)
+		if err != nil {
+			fmt.Printf("find %s: %v\n", email, err)
+		} else {
+			fmt.Printf("find %s: %v\n", email, id)
+		}
+	}
+
+	for _, email := range []string{"josh_f@GMAIL.com", "MARY.TAN@example.com", "Josh <josh@example.com>"} {
+		_, err := repo.CreateAdmin(ctx, Admin{Name: "Someone", Email: email})
+		fmt.Printf("create %s: %v\n", email, err)
+	}
+
+	clinicId := createWithAdmin(ctx, repo)
+	updateConcurrently(ctx, repo, clinicId)
+
+	// read twice: loaded from the database, then from the cache
+	for i := 0; i < 2; i++ {
+		_, err := repo.GetClinic(ctx, clinicId)
+		checkErr(err)
+	}
+	s := repo.Cache.Stats()
+	fmt.Printf("cache: %d hits, %d misses, %d invalidations, %d evictions, %d objects\n", s.Hits, s.Misses, s.Invalidations, s.Evictions, s.Size)
+
+	if *addr != "" {
+		http.Handle("/clinics/", ClinicsHandler{Repo: repo, Timeout: *timeout})
+		fmt.Printf("serving http://%s/clinics/%s\n", *addr, string(clinicId))
+		log.Fatal(http.ListenAndServe(*addr, nil))
+	}
+}
+
+// createWithAdmin adds an admin and the clinic it created in one unit of
+// work, and then tries again with an admin whose email is taken: neither
+// that admin nor its clinic is written.
+func createWithAdmin(ctx context.Context, repo *Repository) quad.IRI {
+	var clinicId quad.IRI
+	for _, email := range []string{"ann.lim@example.com", "Josh_F@gmail.com"} {
+		a := Admin{Name: "Ann", Email: email, HashedPassword: "u9eu8oue35"}
+		c := Clinic{
+			Name:      "Healthy Life",
+			Address1:  "11 boar st, Singapore 11233",
+			OfficeTel: "65 6100 0939",
+			Hours: []OpeningHours{
+				{DayOfWeek: "schema:Monday", Slot: 1, Opens: "08:00", Closes: "12:00"},
+			},
+		}
+
+		c.Creator = &a // written as a link to a, see UnitOfWork
+
+		u := repo.Begin()
+		_, err := u.Add(&c)
+		checkErr(err)
+		_, err = u.Add(&a)
+		checkErr(err)
+		err = u.Commit(ctx)
+		fmt.Printf("create %s with clinic %v: %v\n", email, c.ID, err)
+		if err == nil {
+			clinicId = c.ID
+		} else if _, err := repo.GetClinic(ctx, c.ID); err != nil {
+			fmt.Printf("get clinic %v: %v\n", c.ID, err)
+		}
+	}
+	return clinicId
+}
+
+// updateConcurrently shows two operators editing a clinic at the same time:
+// the second update is made from a version that is not the current one
+// anymore, and fails instead of undoing the first.
+func updateConcurrently(ctx context.Context, repo *Repository, id quad.IRI) {
+	a, err := repo.GetClinic(ctx, id)
+	checkErr(err)
+	b, err := repo.GetClinic(ctx, id)
+	checkErr(err)
+	fmt.Println("both read version", a.Version)
+
+	a.OfficeTel = "65 6100 0940"
+	version, err := repo.UpdateClinic(ctx, a, a.Version)
+	checkErr(err)
+	fmt.Println("A changed the phone: version", version)
+
+	b.Hours = append(b.Hours, OpeningHours{DayOfWeek: "schema:Monday", Slot: 2, Opens: "14:00", Closes: "18:00"})
+	_, err = repo.UpdateClinic(ctx, b, b.Version)
+	fmt.Println("B added hours:", err)
+
+	// read again, and make the change again
+	b, err = repo.GetClinic(ctx, id)
+	checkErr(err)
+	b.Hours = append(b.Hours, OpeningHours{DayOfWeek: "schema:Monday", Slot: 2, Opens: "14:00", Closes: "18:00"})
+	version, err = repo.UpdateClinic(ctx, b, b.Version)
+	checkErr(err)
+	fmt.Printf("B added hours: version %d, phone %s, %d opening hours\n", version, b.OfficeTel, len(b.Hours))
+}
+
+// reopenWhileLocked opens the database again, the way another program
+// would, while store has it open, and so locked. The first time, store
+// keeps it open, and OpenStore gives up. The second time, store closes it
+// after 300ms, and the new handle is returned.
+func reopenWhileLocked(ctx context.Context, store *cayley.Handle, path string) *cayley.Handle {
+	_, err := OpenStore(ctx, path, 100*time.Millisecond, DefaultRetry)
+	fmt.Println("open while locked:", err)
+
+	time.AfterFunc(300*time.Millisecond, func() { store.Close() })
+	other, err := OpenStore(ctx, path, 100*time.Millisecond, DefaultRetry)
+	fmt.Println("open while locked for 300ms:", err)
+	checkErr(err)
+	return other
+}
+
+func checkErr(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func initializeAndOpenGraph(dbFile string) *cayley.Handle {
+	graph.InitQuadStore("bolt", dbFile, nil)
+
+	// Open and use the database
+	store, err := cayley.NewGraph("bolt", dbFile, nil)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return store
+}
+
+func insert(h *cayley.Handle, o interface{}) (quad.Value, error) {
+	qw := graph.NewWriter(h)
+	id, err := schema.WriteAsQuads(qw, o)
+	// don't forget to close a writer; it has some internal buffering, and
+	// writing what is left of it can fail too
+	if cerr := qw.Close(); err == nil {
+		err = cerr
+	}
+	return id, err
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/voc/rdf"
+)
+
+// MigratedEmail is an admin whose email was normalized, or not when Err is
+// set.
+type MigratedEmail struct {
+	Admin quad.IRI
+	From  string // as it is stored
+	To    string // normalized, see NormalizeEmail
+	Err   error  // the email is invalid or taken, and was left as it is
+}
+
+// MigrateEmails normalizes the emails of the admins written before the
+// repository, ex: by insert() in the earlier guides, and claims them, see
+// emailClaim, so that FindAdminID finds them and CreateAdmin can't give
+// them to another admin. Run it once before using a store of the earlier
+// guides with the repository.
+//
+// Every admin is converted in a transaction of its own. Admins already
+// converted are skipped, so the migration can be run again, ex: after the
+// emails it reports were fixed by hand. When two admins have the same
+// email, the one that had it normalized keeps it, or else the first one
+// found. With dryRun, nothing is written.
+func (r *Repository) MigrateEmails(ctx context.Context, dryRun bool) ([]MigratedEmail, error) {
+	var admins []MigratedEmail
+	p := cayley.StartPath(r.store).Has(quad.IRI(rdf.Type), quad.IRI("Admin")).Tag("admin").Out(quad.IRI("email")).Tag("email")
+	err := p.Iterate(ctx).TagValues(nil, func(tags map[string]quad.Value) {
+		id, _ := tags["admin"].(quad.IRI)
+		email, _ := tags["email"].(quad.String) // not quad.StringOf, which quotes it
+		admins = append(admins, MigratedEmail{Admin: id, From: string(email)})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the owner of each email: the admins that have it normalized first
+	owners := make(map[string]quad.IRI)
+	for _, normalized := range []bool{true, false} {
+		for i := range admins {
+			m := &admins[i]
+			email, err := NormalizeEmail(m.From)
+			if err != nil || (email == m.From) != normalized {
+				continue
+			}
+			if _, ok := owners[email]; !ok {
+				owners[email] = m.Admin
+			}
+		}
+	}
+
+	var found []MigratedEmail
+	for _, m := range admins {
+		m.To, m.Err = NormalizeEmail(m.From)
+		if m.Err == nil && owners[m.To] != m.Admin {
+			m.Err = &EmailTakenError{Email: m.To, Admin: owners[m.To]}
+		}
+		if m.Err == nil && m.To == m.From && r.store.ValueOf(emailClaim(m.To).Subject) != nil {
+			continue // converted already
+		}
+		if m.Err == nil && !dryRun {
+			m.Err = r.migrateEmail(ctx, m)
+			if _, ok := m.Err.(*EmailTakenError); m.Err != nil && !ok {
+				return found, m.Err
+			}
+		}
+		found = append(found, m)
+	}
+	return found, nil
+}
+
+// migrateEmail writes the normalized email of an admin and its claim, in
+// one transaction.
+func (r *Repository) migrateEmail(ctx context.Context, m MigratedEmail) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	tx := cayley.NewTransaction()
+	if m.To != m.From {
+		tx.RemoveQuad(quad.Make(m.Admin, quad.IRI("email"), quad.String(m.From), nil))
+		tx.AddQuad(quad.Make(m.Admin, quad.IRI("email"), quad.String(m.To), nil))
+	}
+	tx.AddQuad(emailClaim(m.To))
+	err := r.apply(ctx, tx)
+	if graph.IsQuadExist(err) {
+		// claimed by an admin created with the repository since
+		if other, ferr := r.FindAdminID(ctx, m.To); ferr == nil && other != m.Admin {
+			return &EmailTakenError{Email: m.To, Admin: other}
+		}
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+)
+
+// Repository reads and writes admins and clinics, and keeps the rules the
+// store doesn't know about, ex: one admin per email.
+type Repository struct {
+	store *cayley.Handle
+	Retry RetryPolicy // of writes, DefaultRetry unless changed
+	mu    sync.Mutex  // held while a transaction is checked and applied, see apply
+
+	// Timeout bounds each operation whose context has no deadline, ex: a
+	// command that reads with context.Background(); none if 0.
+	Timeout time.Duration
+
+	// Cache keeps the admins and clinics read by GetAdmin and GetClinic;
+	// none if nil.
+	Cache *Cache
+}
+
+// NewRepository returns a repository of the objects of a store.
+func NewRepository(store *cayley.Handle) *Repository {
+	return &Repository{store: store, Retry: DefaultRetry}
+}
+
+// withTimeout returns the context of an operation: ctx, with a deadline
+// Timeout from now if it has none.
+func (r *Repository) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || r.Timeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.Timeout)
+}
+
+// txWriter adds the quads written by schema.WriteAsQuads to a transaction.
+type txWriter struct {
+	tx *graph.Transaction
+}
+
+func (w txWriter) WriteQuad(q quad.Quad) error {
+	w.tx.AddQuad(q)
+	return nil
+}
+
+func (w txWriter) WriteQuads(buf []quad.Quad) (int, error) {
+	for _, q := range buf {
+		w.tx.AddQuad(q)
+	}
+	return len(buf), nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/kv"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	hbolt "github.com/hidal-go/hidalgo/kv/bolt"
+)
+
+// RetryPolicy is how to retry what failed for a reason that goes away by
+// itself, ex: the database is locked by another process, see OpenStore.
+type RetryPolicy struct {
+	Attempts   int           // in all, 1 to never retry
+	Backoff    time.Duration // before the second attempt, then twice as long each time
+	MaxBackoff time.Duration
+}
+
+var DefaultRetry = RetryPolicy{Attempts: 5, Backoff: 50 * time.Millisecond, MaxBackoff: time.Second}
+
+// WriteError is a write that failed, after Attempts attempts.
+type WriteError struct {
+	Err       error
+	Transient bool // the last attempt failed for a reason that can go away
+	Attempts  int
+}
+
+func (e *WriteError) Error() string {
+	if e.Transient {
+		return fmt.Sprintf("write failed %d times: %v", e.Attempts, e.Err)
+	}
+	return fmt.Sprintf("write failed: %v", e.Err)
+}
+
+func (e *WriteError) Unwrap() error { return e.Err }
+
+// IsTransient tells if something failed for a reason that can go away by
+// itself, so it can be tried again: the database was locked when it was
+// opened, see OpenStore, or the error says it is temporary, like network
+// errors do.
+func IsTransient(err error) bool {
+	if errors.Is(err, bolt.ErrTimeout) {
+		return true
+	}
+	var t interface{ Temporary() bool }
+	return errors.As(err, &t) && t.Temporary()
+}
+
+// do calls f until it succeeds, fails for a reason that is not transient,
+// the attempts are used up, or ctx is done, waiting longer after each
+// failure. It returns the number of attempts, and the error of the last
+// one, or of ctx.
+func (p RetryPolicy) do(ctx context.Context, f func() error) (int, error) {
+	backoff := p.Backoff
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return attempt - 1, err
+		}
+		err := f()
+		if err == nil || !IsTransient(err) || attempt >= p.Attempts {
+			return attempt, err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		}
+		if backoff *= 2; backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+// OpenStore opens a bolt database made by graph.InitQuadStore. bolt locks
+// the file while it is open, so it can't be opened while another program
+// has it open: cayley.NewGraph waits for the lock as long as it takes.
+// OpenStore waits for up to timeout, after which bolt gives up with
+// bolt.ErrTimeout, and tries again as set by p.
+func OpenStore(ctx context.Context, path string, timeout time.Duration, p RetryPolicy) (*cayley.Handle, error) {
+	var db *hbolt.DB
+	attempts, err := p.do(ctx, func() error {
+		var err error
+		db, err = hbolt.Open(filepath.Join(path, "indexes.bolt"), &bolt.Options{Timeout: timeout})
+		return err
+	})
+	if err != nil && IsTransient(err) {
+		return nil, fmt.Errorf("open %s: gave up after %d attempts: %w", path, attempts, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	qs, err := kv.New(db, nil)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	qw, err := graph.NewQuadWriter("single", qs, nil)
+	if err != nil {
+		qs.Close()
+		return nil, err
+	}
+	return &cayley.Handle{QuadStore: qs, QuadWriter: qw}, nil
+}
+
+// retry calls write again after a transient failure, as set by the Retry
+// policy, see RetryPolicy.do. write must be safe to call again after a
+// failure: see apply and Insert. Once open, bolt doesn't fail that way, but
+// the stores of cayley that are reached over the network do.
+//
+// Errors from the store about the quads, ex: graph.IsQuadExist, are
+// returned as they are, since callers turn them into errors of their own,
+// and so are those of ctx.
+func (r *Repository) retry(ctx context.Context, write func() error) error {
+	attempts, err := r.Retry.do(ctx, write)
+	if err == nil || err == ctx.Err() || graph.IsQuadExist(err) || graph.IsQuadNotExist(err) {
+		return err
+	}
+	return &WriteError{Err: err, Transient: IsTransient(err), Attempts: attempts}
+}
+
+// apply applies a transaction. The store applies all of it or none, so a
+// failed transaction can be applied again. The store doesn't take a
+// context: once it has started, a transaction is applied even if ctx is
+// done meanwhile, and that is not an error.
+//
+// The transaction is checked first, see check, and is only given to the
+// store if it can be applied.
+func (r *Repository) apply(ctx context.Context, tx *graph.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// also when it fails, which costs a load at most
+	defer r.Cache.invalidate(tx)
+	if err := r.check(ctx, tx); err != nil {
+		return err
+	}
+	return r.retry(ctx, func() error {
+		return r.store.ApplyTransaction(tx)
+	})
+}
+
+// Insert writes an object without the checks of the Create methods, ex: to
+// import data, and returns its ID. o is a pointer to a struct with an ID
+// field, see UnitOfWork.Add.
+//
+// A writer, like the one of insert(), sends quads in batches, so a part of
+// the object can be written when it fails, and writing the object again
+// gives its opening hours new IDs: they would be there twice. Insert makes
+// the quads once, with all IDs, and writes them in one transaction, which
+// can be applied again after a failure.
+func (r *Repository) Insert(ctx context.Context, o interface{}) (quad.IRI, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	id, err := assignID(o)
+	if err != nil {
+		return "", err
+	}
+	tx := cayley.NewTransaction()
+	if _, err := schema.WriteAsQuads(txWriter{tx}, o); err != nil {
+		return "", err
+	}
+	return id, r.apply(ctx, tx)
+}
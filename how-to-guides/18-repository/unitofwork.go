@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+)
+
+var (
+	ErrCommitted = errors.New("unit of work already committed")
+	ErrNotAdded  = errors.New("links to an object not added to the unit of work")
+)
+
+// UnitOfWork writes several new objects, ex: an admin and the clinic it
+// created, in one transaction: either all of them are written, or none.
+//
+//	u := repo.Begin()
+//	clinic.Creator = &admin
+//	u.Add(&clinic)
+//	u.Add(&admin)
+//	err := u.Commit(ctx)
+//
+// Objects added together link to each other with pointers, ex: Creator.
+// schema.WriteAsQuads would write the object a pointer points to a second
+// time, so these fields are not written: Commit sets the ID field next to
+// each, ex: CreatedBy, to the ID of the object it points to, which must have
+// been added too. To link to an object that is already stored, set the ID
+// field instead. Objects are written as they are at Commit, and the rules
+// of CreateAdmin and CreateClinic apply.
+type UnitOfWork struct {
+	repo      *Repository
+	objects   []interface{} // pointers to structs, in the order they were added
+	committed bool
+}
+
+// Begin starts a unit of work.
+func (r *Repository) Begin() *UnitOfWork {
+	return &UnitOfWork{repo: r}
+}
+
+// Add stages an object, a pointer to a struct with a quad.IRI field tagged
+// quad:"@id", and returns its ID. An object without an ID gets a new one.
+// Admins are checked now, see checkEmails, and again at Commit, with the
+// emails they have then.
+func (u *UnitOfWork) Add(o interface{}) (quad.IRI, error) {
+	if u.committed {
+		return "", ErrCommitted
+	}
+	id, err := assignID(o)
+	if err != nil {
+		return "", err
+	}
+	objects := append(u.objects, o)
+	if _, err := checkEmails(objects); err != nil {
+		return "", err
+	}
+	u.objects = objects
+	return id, nil
+}
+
+// added tells if o, a pointer, was added to the unit of work.
+func (u *UnitOfWork) added(o interface{}) bool {
+	for _, other := range u.objects {
+		if other == o {
+			return true
+		}
+	}
+	return false
+}
+
+// checkEmails normalizes the emails of the admins among objects, see
+// NormalizeEmail, and checks that no two of them have the same one. It
+// returns the admin of each email.
+func checkEmails(objects []interface{}) (map[string]quad.IRI, error) {
+	emails := make(map[string]quad.IRI)
+	for _, o := range objects {
+		a, ok := o.(*Admin)
+		if !ok {
+			continue
+		}
+		email, err := NormalizeEmail(a.Email)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := emails[email]; ok && other != a.ID {
+			return nil, &EmailTakenError{Email: email, Admin: other}
+		}
+		a.Email = email
+		emails[email] = a.ID
+	}
+	return emails, nil
+}
+
+// assignID returns the ID of o, a pointer to a struct with a quad.IRI field
+// tagged quad:"@id", after setting it to a new one if it is empty.
+func assignID(o interface{}) (quad.IRI, error) {
+	rv := reflect.ValueOf(o)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return "", fmt.Errorf("%T is not a pointer to a struct", o)
+	}
+	rv = rv.Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		if f.Tag.Get("quad") != "@id" || f.Type != reflect.TypeOf(quad.IRI("")) {
+			continue
+		}
+		id := rv.Field(i)
+		if id.Interface().(quad.IRI) == "" {
+			id.Set(reflect.ValueOf(schema.GenerateID(o)))
+		}
+		return id.Interface().(quad.IRI), nil
+	}
+	return "", fmt.Errorf("%T has no quad.IRI field tagged quad:\"@id\"", o)
+}
+
+// Commit writes the objects in one transaction. Nothing is written if one of
+// them can't be, ex: an admin whose email another admin has.
+func (u *UnitOfWork) Commit(ctx context.Context) error {
+	ctx, cancel := u.repo.withTimeout(ctx)
+	defer cancel()
+	if u.committed {
+		return ErrCommitted
+	}
+	// an email can have been changed since Add
+	emails, err := checkEmails(u.objects)
+	if err != nil {
+		return err
+	}
+
+	tx := cayley.NewTransaction()
+	for _, o := range u.objects {
+		switch o := o.(type) {
+		case *Admin:
+			if id, err := u.repo.FindAdminID(ctx, o.Email); err == nil && id != o.ID {
+				return &EmailTakenError{Email: o.Email, Admin: id}
+			} else if err != nil && err != ErrAdminNotFound {
+				return err
+			}
+			tx.AddQuad(emailClaim(o.Email))
+			o.Version = 1
+		case *Clinic:
+			if o.Creator != nil {
+				// its ID was set when it was added
+				if !u.added(o.Creator) {
+					return fmt.Errorf("clinic %v: creator %v: %w", o.ID, o.Creator.ID, ErrNotAdded)
+				}
+				o.CreatedBy = o.Creator.ID
+			}
+			o.Version = 1
+		}
+		if _, err := schema.WriteAsQuads(txWriter{tx}, o); err != nil {
+			return err
+		}
+	}
+
+	err = u.repo.apply(ctx, tx)
+	if graph.IsQuadExist(err) {
+		// an admin created by someone else since FindAdminID
+		for email, id := range emails {
+			if other, err := u.repo.FindAdminID(ctx, email); err == nil && other != id {
+				return &EmailTakenError{Email: email, Admin: other}
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	u.committed = true
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/voc/rdf"
+)
+
+// testRepository returns a repository of a store in memory.
+func testRepository(t *testing.T) *Repository {
+	store, err := cayley.NewMemoryGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewRepository(store)
+}
+
+func testClinic() Clinic {
+	return Clinic{
+		Name:      "Healthy Life",
+		Address1:  "11 boar st, Singapore 11233",
+		OfficeTel: "65 6100 0939",
+		Hours: []OpeningHours{
+			{DayOfWeek: "schema:Monday", Slot: 1, Opens: "08:00", Closes: "12:00"},
+		},
+	}
+}
+
+// TestCommitCreator checks that a clinic added with its admin links to it,
+// and that the admin is written once.
+func TestCommitCreator(t *testing.T) {
+	ctx := context.Background()
+	repo := testRepository(t)
+	a := Admin{Name: "Ann", Email: "Ann.Lim@example.com", HashedPassword: "u9eu8oue35"}
+	c := testClinic()
+	c.Creator = &a
+
+	u := repo.Begin()
+	if _, err := u.Add(&c); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.Add(&a); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.GetClinic(ctx, c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CreatedBy != a.ID {
+		t.Errorf("clinic created by %v, want %v", got.CreatedBy, a.ID)
+	}
+	if _, err := repo.GetAdmin(ctx, got.CreatedBy); err != nil {
+		t.Errorf("get admin %v: %v", got.CreatedBy, err)
+	}
+	admins, err := cayley.StartPath(repo.store).Has(quad.IRI(rdf.Type), quad.IRI("Admin")).Iterate(ctx).AllValues(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(admins) != 1 {
+		t.Errorf("%d admins written, want 1: %v", len(admins), admins)
+	}
+}
+
+// TestCommitCreatorNotAdded checks that nothing is written when a clinic
+// links to an admin that was not added.
+func TestCommitCreatorNotAdded(t *testing.T) {
+	ctx := context.Background()
+	repo := testRepository(t)
+	a := Admin{ID: "admin-1", Name: "Ann", Email: "ann.lim@example.com", HashedPassword: "u9eu8oue35"}
+	c := testClinic()
+	c.Creator = &a
+
+	u := repo.Begin()
+	if _, err := u.Add(&c); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Commit(ctx); !errors.Is(err, ErrNotAdded) {
+		t.Fatalf("commit: %v, want ErrNotAdded", err)
+	}
+	if _, err := repo.GetClinic(ctx, c.ID); err != ErrClinicNotFound {
+		t.Errorf("get clinic %v: %v, want ErrClinicNotFound", c.ID, err)
+	}
+	if _, err := repo.GetAdmin(ctx, a.ID); err != ErrAdminNotFound {
+		t.Errorf("get admin %v: %v, want ErrAdminNotFound", a.ID, err)
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	"github.com/cayleygraph/cayley/voc/rdf"
+)
+
+// versionPred is the version of an object, 1 when it is created and one
+// more at every update:
+//
+//	<clinic> <version> "3"^^<xsd:integer> .
+//
+// Objects written before versions were added have none, which is version 0.
+var versionPred = quad.IRI("version")
+
+// ConflictError is returned when an object is updated from a version that
+// is not the current one: someone else updated it since it was read.
+type ConflictError struct {
+	ID       quad.IRI
+	Expected int
+	Current  int // 0 if it was removed
+}
+
+func (e *ConflictError) Error() string {
+	if e.Current == 0 {
+		return fmt.Sprintf("%v was removed or has no version", e.ID)
+	}
+	return fmt.Sprintf("%v was updated: version is %d, not %d", e.ID, e.Current, e.Expected)
+}
+
+// owned lists the predicates to the nodes that belong to an object and are
+// written with it, ex: the opening hours of a clinic. They are replaced
+// with the object.
+var owned = map[quad.IRI]bool{
+	quad.IRI("schema:openingHoursSpecification"): true,
+}
+
+// subjectQuads returns the quads of a node, ex: <clinic> <name> "Heal Now".
+func (r *Repository) subjectQuads(ctx context.Context, id quad.Value) ([]quad.Quad, error) {
+	it := r.store.QuadIterator(quad.Subject, r.store.ValueOf(id))
+	defer it.Close()
+	var quads []quad.Quad
+	for it.Next(ctx) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		quads = append(quads, r.store.Quad(it.Result()))
+	}
+	return quads, it.Err()
+}
+
+// Version returns the version of an object, 0 if it has none.
+func (r *Repository) Version(ctx context.Context, id quad.IRI) (int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	v, err := cayley.StartPath(r.store, id).Out(versionPred).Iterate(ctx).FirstValue(nil)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	n, _ := v.(quad.Int)
+	return int(n), nil
+}
+
+// errWrongType is returned by replace when the node to replace is not of
+// the type of the object, ex: a PUT of a clinic to the ID of an admin.
+var errWrongType = errors.New("not of this type")
+
+// replace adds to tx the quads to write o, whose version is already set to
+// expected+1, in place of the object id, of type typ, and the nodes it owns.
+// The old version quad is removed too: if the object changed since the
+// caller read it, that quad is gone and the transaction fails, see
+// applyUpdate.
+//
+// The type is checked in the quads that are replaced. Removing the type quad
+// can't make the transaction check it too, since writing o adds it back and
+// the two cancel out; the version quad does: the type can't change without a
+// new version.
+func (r *Repository) replace(ctx context.Context, tx *graph.Transaction, id, typ quad.IRI, expected int, o interface{}) error {
+	quads, err := r.subjectQuads(ctx, id)
+	if err != nil {
+		return err
+	}
+	isType := false
+	for _, q := range quads {
+		if q.Predicate == quad.IRI(rdf.Type) && q.Object == typ {
+			isType = true
+		}
+	}
+	if !isType {
+		return errWrongType
+	}
+
+	for _, q := range quads {
+		if expected == 0 && q.Predicate == versionPred {
+			// versioned since the caller read it
+			n, _ := q.Object.(quad.Int)
+			return &ConflictError{ID: id, Current: int(n)}
+		}
+		tx.RemoveQuad(q)
+		if p, ok := q.Predicate.(quad.IRI); ok && owned[p] {
+			children, err := r.subjectQuads(ctx, q.Object)
+			if err != nil {
+				return err
+			}
+			for _, c := range children {
+				tx.RemoveQuad(c)
+			}
+		}
+	}
+	if expected > 0 {
+		// already removed above if it is still the current one
+		tx.RemoveQuad(quad.Make(id, versionPred, quad.Int(expected), nil))
+	}
+
+	_, err = schema.WriteAsQuads(txWriter{tx}, o)
+	return err
+}
+
+// applyUpdate applies a transaction made by replace. It fails with a
+// *ConflictError if one of the quads to remove is not there anymore, or if
+// the new version is already there: another update of an object without a
+// version, made at the same time, wrote version 1 first.
+func (r *Repository) applyUpdate(ctx context.Context, tx *graph.Transaction, id quad.IRI, expected int) error {
+	unpin := pin(tx)
+	err := r.apply(ctx, tx)
+	if graph.IsQuadNotExist(err) || graph.IsQuadExist(err) && failedOn(err, id, versionPred) {
+		current, _ := r.Version(ctx, id)
+		return &ConflictError{ID: id, Expected: expected, Current: current}
+	} else if err != nil {
+		return err
+	}
+	if len(unpin.Deltas) > 0 {
+		// the update is written; a pin left behind only takes room
+		r.apply(ctx, unpin)
+	}
+	return nil
+}
+
+// pinPred links the pins of a transaction to their values, see pin.
+var pinPred = quad.IRI("pin")
+
+// pin works around the bolt store of cayley 0.7: in a transaction that
+// removes a value from more quads than it adds it to, the quads that add it
+// are written without it. Ex: an update of a clinic at version 1 removes
+// "1" from the version and from the slot of its old opening hours, and adds
+// it to the slot of the new ones, which then has none.
+//
+// pin adds to tx a quad for each missing reference of such a value, ex:
+// <pin-id> <pin> "1", and returns the transaction that removes them, to
+// apply after tx. A transaction that only removes quads is not affected.
+func pin(tx *graph.Transaction) *graph.Transaction {
+	refs := make(map[graph.ValueHash]int)
+	added := make(map[graph.ValueHash]quad.Value)
+	for _, d := range tx.Deltas {
+		n := 1
+		if d.Action == graph.Delete {
+			n = -1
+		}
+		for _, dir := range quad.Directions {
+			v := d.Quad.Get(dir)
+			if v == nil {
+				continue
+			}
+			h := graph.HashOf(v)
+			refs[h] += n
+			if n > 0 {
+				added[h] = v
+			}
+		}
+	}
+
+	unpin := cayley.NewTransaction()
+	for h, v := range added {
+		for n := refs[h]; n < 0; n++ {
+			q := quad.Make(schema.GenerateID(nil), pinPred, v, nil)
+			tx.AddQuad(q)
+			unpin.RemoveQuad(q)
+		}
+	}
+	return unpin
+}
+
+// check returns the error the store gives when it can't apply tx, a
+// *graph.DeltaError, without applying it: a quad to add is already there,
+// or one to remove is not.
+//
+// The bolt store of cayley 0.7 keeps in memory the indexes of the quads of
+// a transaction that fails after it added them, ex: on a quad to remove,
+// and writes them with the next transaction, under the IDs of other quads.
+// So a transaction must not fail: apply checks it first, while holding mu.
+// A bolt database is open in one process at a time, and the writes of the
+// repository are the only ones, so nothing can change between the two.
+func (r *Repository) check(ctx context.Context, tx *graph.Transaction) error {
+	stored := make(map[quad.Value]map[quad.Quad]bool)
+	for _, d := range tx.Deltas {
+		s := d.Quad.Subject
+		if stored[s] == nil {
+			stored[s] = make(map[quad.Quad]bool)
+			if r.store.ValueOf(s) != nil {
+				quads, err := r.subjectQuads(ctx, s)
+				if err != nil {
+					return err
+				}
+				for _, q := range quads {
+					stored[s][q] = true
+				}
+			}
+		}
+		if exists := stored[s][d.Quad]; d.Action == graph.Add && exists {
+			return &graph.DeltaError{Delta: d, Err: graph.ErrQuadExists}
+		} else if d.Action == graph.Delete && !exists {
+			return &graph.DeltaError{Delta: d, Err: graph.ErrQuadNotExist}
+		}
+	}
+	return nil
+}
+
+// failedOn tells if a transaction failed on a quad of a subject and predicate.
+func failedOn(err error, subject, predicate quad.Value) bool {
+	var de *graph.DeltaError
+	if !errors.As(err, &de) {
+		return false
+	}
+	q := de.Delta.Quad
+	return q.Subject == subject && q.Predicate == predicate
+}